@@ -0,0 +1,94 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Averager computes AverageChargeRate for batteries the system does not
+// provide it for, as exponential moving average of ChargeRate over repeated reads.
+//
+// It is meant to be kept between calls and passed in Options.Averager.
+// Zero value is ready to use and it is safe for concurrent use.
+type Averager struct {
+	// Time constant of the average, defaults to one minute.
+	Window time.Duration
+
+	mu       sync.Mutex
+	averages map[string]average
+}
+
+type average struct {
+	rate  float64
+	time  time.Time
+	state AgnosticState
+}
+
+// Reset forgets all the previous readings.
+func (a *Averager) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.averages = nil
+}
+
+// update adds b's ChargeRate, read at t by given provider, and sets its AverageChargeRate.
+func (a *Averager) update(provider string, b *Battery, t time.Time) {
+	if b == nil {
+		return
+	}
+	if b.Provenance == nil {
+		if b.AverageChargeRate != 0 {
+			return
+		}
+	} else if b.has(FieldAverageChargeRate) || !b.has(FieldChargeRate) {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	window := a.Window
+	if window <= 0 {
+		window = time.Minute
+	}
+	if a.averages == nil {
+		a.averages = make(map[string]average)
+	}
+
+	key := provider + "\x00" + b.ID
+	rate := b.ChargeRate
+	// Averaging across a change of direction would mix unrelated rates, so it starts over.
+	if prev, ok := a.averages[key]; ok && prev.state == b.State.Raw && t.After(prev.time) {
+		alpha := 1 - math.Exp(-float64(t.Sub(prev.time))/float64(window))
+		rate = prev.rate + alpha*(rate-prev.rate)
+	}
+	a.averages[key] = average{rate, t, b.State.Raw}
+
+	b.AverageChargeRate = rate
+	if b.Provenance != nil {
+		b.setSource(FieldAverageChargeRate, nil, Derived, b.source(FieldChargeRate), "Averager")
+	}
+}
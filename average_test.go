@@ -0,0 +1,91 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAverager(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	measured := Provenance{Measured, "test"}
+
+	cases := []struct {
+		at         time.Duration
+		batteryIn  *Battery
+		batteryOut *Battery
+	}{{
+		0,
+		&Battery{ID: "BAT0", State: State{Raw: Discharging}, ChargeRate: 10000},
+		&Battery{ID: "BAT0", State: State{Raw: Discharging}, ChargeRate: 10000, AverageChargeRate: 10000},
+	}, {
+		time.Minute,
+		&Battery{ID: "BAT0", State: State{Raw: Discharging}, ChargeRate: 20000},
+		&Battery{
+			ID: "BAT0", State: State{Raw: Discharging}, ChargeRate: 20000,
+			AverageChargeRate: 10000 + (1-math.Exp(-1))*10000,
+		},
+	}, {
+		2 * time.Minute,
+		&Battery{ID: "BAT0", State: State{Raw: Charging}, ChargeRate: 5000},
+		&Battery{ID: "BAT0", State: State{Raw: Charging}, ChargeRate: 5000, AverageChargeRate: 5000},
+	}, {
+		2 * time.Minute,
+		&Battery{
+			ID: "BAT1", ChargeRate: 5000,
+			Provenance: map[Field]Provenance{FieldChargeRate: measured},
+		},
+		&Battery{
+			ID: "BAT1", ChargeRate: 5000, AverageChargeRate: 5000,
+			Provenance: map[Field]Provenance{
+				FieldChargeRate:        measured,
+				FieldAverageChargeRate: {Derived, "test, Averager"},
+			},
+		},
+	}, {
+		2 * time.Minute,
+		&Battery{
+			ID: "BAT2", ChargeRate: 5000, AverageChargeRate: 7000,
+			Provenance: map[Field]Provenance{FieldChargeRate: measured, FieldAverageChargeRate: measured},
+		},
+		&Battery{
+			ID: "BAT2", ChargeRate: 5000, AverageChargeRate: 7000,
+			Provenance: map[Field]Provenance{FieldChargeRate: measured, FieldAverageChargeRate: measured},
+		},
+	}, {
+		2 * time.Minute,
+		&Battery{ID: "BAT3", Provenance: map[Field]Provenance{}},
+		&Battery{ID: "BAT3", Provenance: map[Field]Provenance{}},
+	}}
+
+	var a Averager
+	for i, c := range cases {
+		a.update("test", c.batteryIn, start.Add(c.at))
+
+		if !reflect.DeepEqual(c.batteryIn, c.batteryOut) {
+			t.Errorf("%d: %v != %v", i, c.batteryIn, c.batteryOut)
+		}
+	}
+}
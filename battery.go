@@ -36,7 +36,12 @@
 package battery
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"time"
 )
 
 // AgnosticState type enumerates possible battery states, using platform agnostic naming.
@@ -78,6 +83,9 @@ func (s AgnosticState) String() string {
 type State struct {
 	Raw      AgnosticState
 	specific string
+	// Whether Raw was inferred from the other values, because the system
+	// reported Unknown or Undefined state. Explain still returns what the system reported.
+	Inferred bool
 }
 
 func (s State) Explain() string {
@@ -89,12 +97,49 @@ func (s State) String() string {
 }
 
 func (s State) GoString() string {
+	if s.Inferred {
+		return fmt.Sprintf("%s (inferred, %s)", s.Raw, s.specific)
+	}
 	return fmt.Sprintf("%s (%s)", s.Raw, s.specific)
 }
 
+// Kind type tells what a battery powers.
+type Kind int8
+
+const (
+	// KindUnknown is used when the backend cannot tell what the battery powers.
+	KindUnknown Kind = iota
+	// KindSystem is a battery powering the computer itself.
+	KindSystem
+	// KindUPS is an uninterruptible power supply.
+	KindUPS
+	// KindPeripheral is a battery of a connected device, e.g. a wireless mouse.
+	KindPeripheral
+)
+
+var kinds = map[Kind]string{
+	KindUnknown:    "Unknown",
+	KindSystem:     "System",
+	KindUPS:        "UPS",
+	KindPeripheral: "Peripheral",
+}
+
+func (k Kind) String() string {
+	return kinds[k]
+}
+
 // Battery type represents a single battery entry information.
 type Battery struct {
+	// Stable identifier of the battery, unique within its provider.
+	// It is the sysfs device name on Linux, the device path on Windows,
+	// the serial number on macOS and FreeBSD (falling back to the battery index
+	// and unit name respectively) and the device name on other BSDs and Solaris.
+	ID string
+	// Human readable name of the battery.
+	// Model name where available, but falls back to ID on Linux.
 	Name string
+	// What the battery powers.
+	Kind Kind
 	// Current battery state.
 	State State
 	// Current (momentary) capacity (in %).
@@ -117,12 +162,208 @@ type Battery struct {
 	// but getting `Voltage` succeeds, this field will have
 	// the same value as `Voltage`, for convenience.
 	DesignVoltage float64
+	// Current (momentary) net power flowing into the battery (in mW).
+	// It is positive while charging and negative while discharging,
+	// regardless of the sign convention used by the underlying driver.
+	NetPower float64
+	// Charge values below are read as reported where the system uses
+	// charge units, otherwise they are derived from energy and voltage.
+	// Provenance tells which one it was.
+
+	// Current (momentary) charge (in mAh).
+	ChargeNow float64
+	// Last known full charge (in mAh).
+	ChargeFull float64
+	// Reported design charge (in mAh).
+	ChargeDesign float64
+	// Current (momentary) current (in mA).
+	// It is always non-negative, same as ChargeRate.
+	CurrentNow float64
+	// Remaining time until empty, as estimated by the firmware or the system.
+	// It is zero and missing from Provenance if there is no estimate,
+	// e.g. when not discharging or if the system does not provide one.
+	FirmwareTimeToEmpty time.Duration
+	// Remaining time until full, as estimated by the firmware or the system.
+	// Same rules as for FirmwareTimeToEmpty apply.
+	FirmwareTimeToFull time.Duration
+	// Charge rate averaged over time (in mW), less jumpy than ChargeRate.
+	// It is always non-negative, same as ChargeRate. It is zero and missing
+	// from Provenance if the system does not provide it, unless Options.Averager is used.
+	AverageChargeRate float64
+	// Number of charge cycles the battery went through. If it is not known,
+	// it is zero and its error is ErrValueNotFound if the system does not report it,
+	// ErrUnsupported if the backend cannot read it at all and ErrUnknownValue if
+	// the firmware reports 0, as many do when they do not count cycles.
+	CycleCount int
+	// Temperature of the battery (in °C). If it is not known, it is zero
+	// and its error is ErrValueNotFound if the system does not report it
+	// or ErrUnsupported if the backend cannot read it at all.
+	Temperature float64
+
+	// Identity of the battery pack, each value being empty if not known.
+	Manufacturer    string
+	Model           string
+	Serial          string
+	Chemistry       Chemistry
+	ManufactureDate time.Time
+
+	// Where the values of particular fields come from and whether they were
+	// measured, derived or copied. Only successfully retrieved fields are present.
+	Provenance map[Field]Provenance `json:",omitempty"`
+	// Untouched key/value pairs the backend read from the system,
+	// e.g. the uevent contents on Linux, the ioreg plist on macOS
+	// or the members of the ioctl structs on Windows and FreeBSD.
+	// Only filled if requested with Options.Attributes.
+	Attributes map[string]string `json:",omitempty"`
+	// Implausible readings, as checked according to Options.Validation.
+	Warnings []Warning `json:",omitempty"`
+	// Names of the quirks applied to correct the values, see Quirk.
+	Quirks []string `json:",omitempty"`
 }
 
 func (b *Battery) String() string {
 	return fmt.Sprintf("%+v", *b)
 }
 
+// float returns pointer to the value of f, nil for non-numeric fields.
+func (b *Battery) float(f Field) *float64 {
+	switch f {
+	case FieldCapacity:
+		return &b.Capacity
+	case FieldCurrent:
+		return &b.Current
+	case FieldFull:
+		return &b.Full
+	case FieldDesign:
+		return &b.Design
+	case FieldChargeRate:
+		return &b.ChargeRate
+	case FieldVoltage:
+		return &b.Voltage
+	case FieldDesignVoltage:
+		return &b.DesignVoltage
+	case FieldNetPower:
+		return &b.NetPower
+	case FieldChargeNow:
+		return &b.ChargeNow
+	case FieldChargeFull:
+		return &b.ChargeFull
+	case FieldChargeDesign:
+		return &b.ChargeDesign
+	case FieldCurrentNow:
+		return &b.CurrentNow
+	case FieldAverageChargeRate:
+		return &b.AverageChargeRate
+	case FieldTemperature:
+		return &b.Temperature
+	}
+	return nil
+}
+
+// celsius converts temperature in K to °C.
+func celsius(kelvin float64) float64 {
+	return kelvin - 273.15
+}
+
+// setCycleCount stores cycle count read from source, with 0 meaning unknown.
+func (b *Battery) setCycleCount(e *ErrPartial, count int64, err error, source string) {
+	if err == nil && count <= 0 {
+		count, err = 0, ErrUnknownValue
+	}
+	b.CycleCount = int(count)
+	e.Set(FieldCycleCount, err)
+	b.setSource(FieldCycleCount, err, Measured, source)
+}
+
+func (b *Battery) setAttribute(key, value string) {
+	if b.Attributes == nil {
+		b.Attributes = make(map[string]string)
+	}
+	b.Attributes[key] = value
+}
+
+// setAttributes flattens nested dictionaries using dot separated keys.
+func (b *Battery) setAttributes(prefix string, attributes map[string]interface{}) {
+	for k, v := range attributes {
+		if dict, ok := v.(map[string]interface{}); ok {
+			b.setAttributes(prefix+k+".", dict)
+			continue
+		}
+		b.setAttribute(prefix+k, fmt.Sprint(v))
+	}
+}
+
+// postprocess validates the values and fills in the ones common to all backends.
+// It has to be called by each backend once everything it reads is set.
+func postprocess(b *Battery, e *ErrPartial, opts Options, hints stateHints) {
+	applyQuirks(b, e, opts, runtime.GOOS)
+	switch opts.Validation {
+	case ValidateReport:
+		b.Warnings = b.Validate()
+	case ValidateNormalize:
+		b.Warnings = b.Normalize()
+	}
+	inferState(b, e, hints)
+	normalizeRate(b, e)
+	deriveCharge(b, e, opts)
+}
+
+// normalizeRate sets NetPower and makes ChargeRate and AverageChargeRate non-negative.
+//
+// Drivers do not agree on the sign of the rate they report, so the sign
+// is decided by State, falling back to the driver's one if State does
+// not say which way the energy flows.
+func normalizeRate(b *Battery, e *ErrPartial) {
+	b.AverageChargeRate = math.Abs(b.AverageChargeRate)
+	e.Set(FieldNetPower, e.ChargeRate)
+	if e.ChargeRate != nil {
+		return
+	}
+	rate := math.Abs(b.ChargeRate)
+	b.NetPower = rate
+	switch {
+	case e.State == nil && b.State.Raw == Charging:
+	case e.State == nil && (b.State.Raw == Discharging || b.State.Raw == Empty):
+		b.NetPower = -rate
+	case b.ChargeRate < 0:
+		b.NetPower = -rate
+	}
+	b.ChargeRate = rate
+	b.setSource(FieldNetPower, nil, Derived, b.source(FieldChargeRate), b.source(FieldState))
+}
+
+// withContext runs f, returning early with the context error if ctx is done first.
+// In such case f is left running in the background.
+func withContext(ctx context.Context, f func()) error {
+	if ctx.Done() == nil {
+		f()
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// contextError replaces fatal errors with the context error, if ctx is done.
+// The actual failure is most likely a consequence of cancellation then.
+func contextError(ctx context.Context, err error) error {
+	if _, ok := err.(ErrFatal); ok && ctx.Err() != nil {
+		return ErrFatal{ctx.Err()}
+	}
+	return err
+}
+
 func get(sg func(idx int) (*Battery, error), idx int) (*Battery, error) {
 	b, err := sg(idx)
 	return b, wrapError(err)
@@ -136,7 +377,16 @@ func get(sg func(idx int) (*Battery, error), idx int) (*Battery, error) {
 //
 // If error != nil, it will be either ErrFatal or ErrPartial.
 func Get(idx int) (*Battery, error) {
-	return get(systemGet, idx)
+	return GetContext(context.Background(), idx)
+}
+
+// GetContext is like Get, but respects cancellation and deadline of ctx.
+//
+// If ctx is done before all the data was retrieved, the error will be
+// either ErrFatal wrapping ctx.Err() or ErrPartial with ctx.Err() set
+// on fields that could not be read in time.
+func GetContext(ctx context.Context, idx int) (*Battery, error) {
+	return GetWithOptions(ctx, idx, Options{})
 }
 
 func getAll(sg func() ([]*Battery, error)) ([]*Battery, error) {
@@ -168,10 +418,94 @@ func getAll(sg func() ([]*Battery, error)) ([]*Battery, error) {
 	return bs, nil
 }
 
+func getByID(sg func() ([]*Battery, error), id string) (*Battery, error) {
+	bs, err := sg()
+	errors, isErrors := err.(Errors)
+	if err != nil && !isErrors {
+		return nil, ErrFatal{err}
+	}
+	for i, b := range bs {
+		if b == nil || b.ID != id {
+			continue
+		}
+		if i < len(errors) {
+			return b, wrapError(errors[i])
+		}
+		return b, nil
+	}
+	return nil, ErrFatal{ErrNotFound}
+}
+
+// GetByID returns battery information for given battery ID.
+//
+// Unlike the index taken by Get, the ID keeps referring to the same
+// physical battery even if other batteries are added or removed.
+//
+// IDs are only unique within a provider. If several providers report
+// the same ID, the first match in GetAll order is returned.
+//
+// If error != nil, it will be either ErrFatal or ErrPartial.
+func GetByID(id string) (*Battery, error) {
+	return GetByIDWithOptions(context.Background(), id, Options{})
+}
+
+// GetByIDWithOptions is like GetByID, but respects ctx and uses given options.
+func GetByIDWithOptions(ctx context.Context, id string, opts Options) (*Battery, error) {
+	b, err := getByID(func() ([]*Battery, error) {
+		return registeredGetAll(ctx, opts)
+	}, id)
+	return b, contextError(ctx, err)
+}
+
 // GetAll returns information about all batteries in the system.
 //
+// Batteries from all registered providers are merged, in registration order.
+// A provider failing completely is only reported if no other provider returned anything.
+// Within a provider, batteries are sorted in natural order of their system names (BAT2 before BAT10).
+//
 // If error != nil, it will be either ErrFatal or Errors.
 // If error is of type Errors, it is guaranteed that length of both returned slices is the same and that i-th error coresponds with i-th battery structure.
 func GetAll() ([]*Battery, error) {
-	return getAll(systemGetAll)
+	return GetAllContext(context.Background())
+}
+
+// GetAllContext is like GetAll, but respects cancellation and deadline of ctx.
+//
+// If ctx is done before all the data was retrieved, the error will be
+// either ErrFatal wrapping ctx.Err() or Errors with ctx.Err() set
+// on fields that could not be read in time.
+func GetAllContext(ctx context.Context) ([]*Battery, error) {
+	return GetAllWithOptions(ctx, Options{})
+}
+
+// naturalLess compares strings treating runs of digits as numbers,
+// so that e.g. "BAT2" sorts before "BAT10".
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da == "" || db == "" {
+			if a[0] != b[0] {
+				return a[0] < b[0]
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+		if len(na) != len(nb) {
+			return len(na) < len(nb)
+		}
+		if na != nb {
+			return na < nb
+		}
+		a, b = a[len(da):], b[len(db):]
+	}
+	return len(a) < len(b)
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
 }
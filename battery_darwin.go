@@ -22,26 +22,42 @@
 package battery
 
 import (
+	"context"
 	"fmt"
-	"math"
 	"os/exec"
+	"time"
 
 	plist "howett.net/plist"
 )
 
 type battery struct {
-	Voltage           int
-	CurrentCapacity   int `plist:"AppleRawCurrentCapacity"`
-	MaxCapacity       int `plist:"AppleRawMaxCapacity"`
-	DesignCapacity    int
-	Amperage          int64
-	FullyCharged      bool
-	IsCharging        bool
-	ExternalConnected bool
+	Serial              string
+	Manufacturer        string
+	DeviceName          string
+	BatterySerialNumber string
+	ManufactureDate     int
+	Voltage             int
+	CurrentCapacity     int `plist:"AppleRawCurrentCapacity"`
+	MaxCapacity         int `plist:"AppleRawMaxCapacity"`
+	DesignCapacity      int
+	Amperage            int64
+	CycleCount          *int64
+	Temperature         *int64
+	FullyCharged        bool
+	IsCharging          bool
+	ExternalConnected   bool
+	AvgTimeToEmpty      int
+	AvgTimeToFull       int
+	InstantTimeToEmpty  int
+
+	attributes map[string]interface{}
 }
 
-func readBatteries() ([]*battery, error) {
-	out, err := exec.Command("ioreg", "-n", "AppleSmartBattery", "-r", "-a").Output()
+func readBatteries(ctx context.Context, opts Options) ([]*battery, error) {
+	out, err := exec.CommandContext(ctx, "ioreg", "-n", "AppleSmartBattery", "-r", "-a").Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -55,19 +71,89 @@ func readBatteries() ([]*battery, error) {
 	if _, err = plist.Unmarshal(out, &data); err != nil {
 		return nil, err
 	}
+
+	if opts.Attributes {
+		var raw []map[string]interface{}
+		if _, err = plist.Unmarshal(out, &raw); err != nil {
+			return nil, err
+		}
+		for i := range data {
+			if i < len(raw) {
+				data[i].attributes = raw[i]
+			}
+		}
+	}
 	return data, nil
 }
 
-func convertBattery(battery *battery) *Battery {
+func convertBattery(battery *battery, idx int, opts Options) (*Battery, error) {
 	volts := float64(battery.Voltage) / 1000
 	b := &Battery{
-		Current:       float64(battery.CurrentCapacity) * volts,
-		Full:          float64(battery.MaxCapacity) * volts,
-		Design:        float64(battery.DesignCapacity) * volts,
-		ChargeRate:    math.Abs(float64(battery.Amperage)) * volts,
+		ID:            battery.Serial,
+		Kind:          KindSystem,
 		Voltage:       volts,
 		DesignVoltage: volts,
+		ChargeNow:     float64(battery.CurrentCapacity),
+		ChargeFull:    float64(battery.MaxCapacity),
+		ChargeDesign:  float64(battery.DesignCapacity),
+		CurrentNow:    float64(battery.Amperage),
+
+		Manufacturer:    battery.Manufacturer,
+		Model:           battery.DeviceName,
+		ManufactureDate: sbsDate(battery.ManufactureDate),
 	}
+	if b.ID == "" {
+		// Newer models report it under a different key.
+		b.ID = battery.BatterySerialNumber
+	}
+	b.Serial = b.ID
+	if b.ID == "" {
+		b.ID = fmt.Sprintf("battery%d", idx)
+	}
+	b.setAttributes("", battery.attributes)
+
+	b.setSource(FieldVoltage, nil, Measured, "Voltage")
+	b.setSource(FieldDesignVoltage, nil, Copied, "Voltage")
+	b.setSource(FieldState, nil, Derived, "ExternalConnected", "IsCharging", "FullyCharged")
+	b.setSource(FieldChargeNow, nil, Measured, "AppleRawCurrentCapacity")
+	b.setSource(FieldChargeFull, nil, Measured, "AppleRawMaxCapacity")
+	b.setSource(FieldChargeDesign, nil, Measured, "DesignCapacity")
+	b.setSource(FieldCurrentNow, nil, Measured, "Amperage")
+	e := ErrPartial{}
+
+	if battery.CycleCount != nil {
+		b.setCycleCount(&e, *battery.CycleCount, nil, "CycleCount")
+	} else {
+		e.Set(FieldCycleCount, ErrValueNotFound)
+	}
+	if battery.Temperature != nil {
+		b.Temperature = float64(*battery.Temperature) / 100 // Hundredths of °C.
+		b.setSource(FieldTemperature, nil, Measured, "Temperature")
+	} else {
+		e.Set(FieldTemperature, ErrValueNotFound)
+	}
+
+	// Estimates are in minutes, 65535 (or 0 if the key is missing) meaning there is none.
+	estimate := func(f Field, d *time.Duration, minutes int, key string) bool {
+		if minutes == 65535 || minutes == 0 {
+			return false
+		}
+		*d = time.Duration(minutes) * time.Minute
+		b.setSource(f, nil, Measured, key)
+		return true
+	}
+	if !estimate(FieldFirmwareTimeToEmpty, &b.FirmwareTimeToEmpty, battery.AvgTimeToEmpty, "AvgTimeToEmpty") {
+		estimate(FieldFirmwareTimeToEmpty, &b.FirmwareTimeToEmpty, battery.InstantTimeToEmpty, "InstantTimeToEmpty")
+	}
+	estimate(FieldFirmwareTimeToFull, &b.FirmwareTimeToFull, battery.AvgTimeToFull, "AvgTimeToFull")
+
+	chargeToEnergy(b, &e, FieldCurrent, FieldChargeNow, opts)
+	chargeToEnergy(b, &e, FieldFull, FieldChargeFull, opts)
+	chargeToEnergy(b, &e, FieldDesign, FieldChargeDesign, opts)
+	chargeToEnergy(b, &e, FieldChargeRate, FieldCurrentNow, opts)
+	// Amperage is already averaged by the gauge and no other average is reported,
+	// so AverageChargeRate is left to Options.Averager instead of copying ChargeRate.
+
 	switch {
 	case !battery.ExternalConnected:
 		b.State.Raw = Discharging
@@ -88,11 +174,13 @@ func convertBattery(battery *battery) *Battery {
 		b.State.Raw = Undefined
 		b.State.specific = fmt.Sprintf("%+v", *battery)
 	}
-	return b
+
+	postprocess(b, &e, opts, onlineHints(battery.ExternalConnected, "ExternalConnected"))
+	return b, e
 }
 
-func systemGet(idx int) (*Battery, error) {
-	batteries, err := readBatteries()
+func systemGet(ctx context.Context, idx int, opts Options) (*Battery, error) {
+	batteries, err := readBatteries(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -100,18 +188,19 @@ func systemGet(idx int) (*Battery, error) {
 	if idx >= len(batteries) {
 		return nil, ErrNotFound
 	}
-	return convertBattery(batteries[idx]), nil
+	return convertBattery(batteries[idx], idx, opts)
 }
 
-func systemGetAll() ([]*Battery, error) {
-	_batteries, err := readBatteries()
+func systemGetAll(ctx context.Context, opts Options) ([]*Battery, error) {
+	_batteries, err := readBatteries(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	batteries := make([]*Battery, len(_batteries))
+	errors := make(Errors, len(_batteries))
 	for i, battery := range _batteries {
-		batteries[i] = convertBattery(battery)
+		batteries[i], errors[i] = convertBattery(battery, i, opts)
 	}
-	return batteries, nil
+	return batteries, errors
 }
@@ -0,0 +1,136 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIDDarwin(t *testing.T) {
+	cases := []struct {
+		in     battery
+		idx    int
+		id     string
+		serial string
+	}{
+		{battery{Serial: "S1"}, 0, "S1", "S1"},
+		{battery{BatterySerialNumber: "S2"}, 1, "S2", "S2"},
+		{battery{}, 1, "battery1", ""},
+	}
+
+	for i, c := range cases {
+		b, _ := convertBattery(&c.in, c.idx, Options{})
+
+		if b.ID != c.id {
+			t.Errorf("%d: %v != %v", i, b.ID, c.id)
+		}
+		if b.Serial != c.serial {
+			t.Errorf("%d: %v != %v", i, b.Serial, c.serial)
+		}
+	}
+}
+
+func TestTemperatureDarwin(t *testing.T) {
+	temperature := int64(3095)
+	cases := []struct {
+		in       battery
+		out      float64
+		errorOut error
+	}{
+		{battery{Temperature: &temperature}, 30.95, nil},
+		{battery{}, 0, ErrValueNotFound},
+	}
+
+	for i, c := range cases {
+		b, err := convertBattery(&c.in, 0, Options{})
+
+		if b.Temperature != c.out {
+			t.Errorf("%d: %v != %v", i, b.Temperature, c.out)
+		}
+		if e := err.(ErrPartial).Get(FieldTemperature); e != c.errorOut {
+			t.Errorf("%d: %v != %v", i, e, c.errorOut)
+		}
+	}
+}
+
+func TestCycleCountDarwin(t *testing.T) {
+	count := int64(42)
+	zero := int64(0)
+	cases := []struct {
+		in       battery
+		countOut int
+		errOut   error
+	}{
+		{battery{CycleCount: &count}, 42, nil},
+		{battery{CycleCount: &zero}, 0, ErrUnknownValue},
+		{battery{}, 0, ErrValueNotFound},
+	}
+
+	for i, c := range cases {
+		b, err := convertBattery(&c.in, 0, Options{})
+
+		if b.CycleCount != c.countOut {
+			t.Errorf("%d: %v != %v", i, b.CycleCount, c.countOut)
+		}
+		if e := err.(ErrPartial).Get(FieldCycleCount); e != c.errOut {
+			t.Errorf("%d: %v != %v", i, e, c.errOut)
+		}
+	}
+}
+
+func TestEstimatesDarwin(t *testing.T) {
+	cases := []struct {
+		in              battery
+		toEmpty, toFull time.Duration
+	}{
+		{battery{AvgTimeToEmpty: 90, InstantTimeToEmpty: 80, AvgTimeToFull: 65535}, 90 * time.Minute, 0},
+		{battery{AvgTimeToEmpty: 65535, InstantTimeToEmpty: 80, AvgTimeToFull: 65535}, 80 * time.Minute, 0},
+		{battery{InstantTimeToEmpty: 80, AvgTimeToFull: 30}, 80 * time.Minute, 30 * time.Minute},
+		{battery{}, 0, 0},
+	}
+
+	for i, c := range cases {
+		b, _ := convertBattery(&c.in, 0, Options{})
+
+		if b.FirmwareTimeToEmpty != c.toEmpty {
+			t.Errorf("%d: %v != %v", i, b.FirmwareTimeToEmpty, c.toEmpty)
+		}
+		if b.FirmwareTimeToFull != c.toFull {
+			t.Errorf("%d: %v != %v", i, b.FirmwareTimeToFull, c.toFull)
+		}
+		if has := b.has(FieldFirmwareTimeToEmpty); has != (c.toEmpty != 0) {
+			t.Errorf("%d: %v != %v", i, has, c.toEmpty != 0)
+		}
+	}
+}
+
+func TestAverageChargeRateDarwin(t *testing.T) {
+	b, _ := convertBattery(&battery{Voltage: 12000, Amperage: -1000}, 0, Options{})
+
+	if b.ChargeRate != 12000 {
+		t.Errorf("%v != %v", b.ChargeRate, 12000)
+	}
+	if b.AverageChargeRate != 0 || b.has(FieldAverageChargeRate) {
+		t.Errorf("%v != %v", b.AverageChargeRate, 0)
+	}
+}
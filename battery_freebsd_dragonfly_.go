@@ -24,9 +24,12 @@
 package battery
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
@@ -40,9 +43,37 @@ func readUint32(bytes []byte) uint32 {
 	return ret
 }
 
+// readString reads 0-terminated C-string, trimming the padding some firmwares add.
+func readString(bytes []byte) string {
+	for i, b := range bytes {
+		if b == 0 {
+			bytes = bytes[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(string(bytes))
+}
+
+// setUint32Attributes stores consecutive uint32 members of an ioctl struct
+// as attributes, named with prefix.
+func setUint32Attributes(b *Battery, prefix string, buf []byte, names ...string) {
+	for i, name := range names {
+		b.setAttribute(prefix+name, strconv.FormatUint(uint64(readUint32(buf[i*4:i*4+4])), 10))
+	}
+}
+
+// bixCycles reads acpi_bix.cycles, 0xffffffff meaning unknown, same as 0.
+func bixCycles(bix []byte) int64 {
+	cycles := readUint32(bix[32:36])
+	if cycles == 0xffffffff {
+		return 0
+	}
+	return int64(cycles)
+}
+
 func uint32ToFloat64(num uint32) (float64, error) {
 	if num == 0xffffffff {
-		return 0, errors.New("Unknown value received")
+		return 0, ErrUnknownValue
 	}
 	return float64(num), nil
 }
@@ -51,14 +82,29 @@ func ioctl_(fd int, nr int64, retptr *[164]byte) error {
 	return ioctl(fd, nr, 'B', unsafe.Sizeof(*retptr), unsafe.Pointer(retptr))
 }
 
-func systemGet(idx int) (*Battery, error) {
+// readACAD reads external power status from the AC adapter.
+func readACAD(fd int) stateHints {
+	var online int32
+	_, _, errno := unix.Syscall(
+		unix.SYS_IOCTL,
+		uintptr(fd),
+		0x40044101, // ACPIIO_ACAD_GET_STATUS
+		uintptr(unsafe.Pointer(&online)),
+	)
+	if errno != 0 {
+		return stateHints{}
+	}
+	return onlineHints(online != 0, "ACPIIO_ACAD_GET_STATUS")
+}
+
+func getByIndex(idx int, opts Options) (*Battery, error) {
 	fd, err := unix.Open("/dev/acpi", unix.O_RDONLY, 0777)
 	if err != nil {
 		return nil, err
 	}
 	defer unix.Close(fd)
 
-	b := &Battery{}
+	b := &Battery{ID: fmt.Sprintf("battery%d", idx), Kind: KindSystem}
 	e := ErrPartial{}
 
 	// No unions in Go, so lets "emulate" union with byte array ;-].
@@ -78,6 +124,44 @@ func systemGet(idx int) (*Battery, error) {
 	b.Full, e.Full = uint32ToFloat64(readUint32(retptr[8:12]))                    // acpi_bif.lfcap
 	b.DesignVoltage, e.DesignVoltage = uint32ToFloat64(readUint32(retptr[16:20])) // acpi_bif.dvol
 	b.DesignVoltage /= 1000
+	b.setSource(FieldDesign, e.Design, Measured, "ACPIIO_BATT_GET_BIF")
+	b.setSource(FieldFull, e.Full, Measured, "ACPIIO_BATT_GET_BIF")
+	b.setSource(FieldDesignVoltage, e.DesignVoltage, Measured, "ACPIIO_BATT_GET_BIF")
+	b.Model = readString(retptr[36:68])                       // acpi_bif.model
+	b.Serial = readString(retptr[68:100])                     // acpi_bif.serial
+	b.Chemistry = parseChemistry(readString(retptr[100:132])) // acpi_bif.type
+	b.Manufacturer = readString(retptr[132:164])              // acpi_bif.oeminfo
+	if opts.Attributes {
+		setUint32Attributes(b, "acpi_bif.", retptr[:36], "units", "dcap", "lfcap", "btech", "dvol", "wcap", "lcap", "gra1", "gra2")
+		b.setAttribute("acpi_bif.model", b.Model)
+		b.setAttribute("acpi_bif.serial", b.Serial)
+		b.setAttribute("acpi_bif.type", readString(retptr[100:132]))
+		b.setAttribute("acpi_bif.oeminfo", b.Manufacturer)
+	}
+
+	// Unit number is only the position of the battery, so it is used
+	// as the ID only if the pack does not report its serial number.
+	// Model names are shared by identical packs, so they are not unique.
+	if b.Serial != "" {
+		b.ID = b.Serial
+	}
+
+	// Extended information (ACPI _BIX) shares the command number with _BIF,
+	// but uses a larger argument. Older kernels do not support it at all.
+	var bix [196]byte
+	*(*int)(unsafe.Pointer(&bix[0])) = idx
+	err = ioctl(fd, 0x10, 'B', unsafe.Sizeof(bix), unsafe.Pointer(&bix)) // ACPIIO_BATT_GET_BIX
+	if err == nil {
+		b.setCycleCount(&e, bixCycles(bix[:]), nil, "ACPIIO_BATT_GET_BIX")
+		if opts.Attributes {
+			setUint32Attributes(b, "acpi_bix.", bix[4:64], "units", "dcap", "lfcap", "btech", "dvol", "wcap", "lcap",
+				"cycles", "accuracy", "stmax", "stmin", "aimax", "aimin", "gra1", "gra2")
+		}
+	} else if err == unix.ENOTTY {
+		e.Set(FieldCycleCount, ErrUnsupported)
+	} else {
+		e.Set(FieldCycleCount, err)
+	}
 
 	*unit = idx
 	err = ioctl_(fd, 0x11, &retptr) // APCIIO_BATT_GET_BST
@@ -100,6 +184,13 @@ func systemGet(idx int) (*Battery, error) {
 		b.Current, e.Current = uint32ToFloat64(readUint32(retptr[8:12]))      // acpi_bst.cap
 		b.Voltage, e.Voltage = uint32ToFloat64(readUint32(retptr[12:16]))     // acpi_bst.volt
 		b.Voltage /= 1000
+		b.setSource(FieldState, nil, Measured, "ACPIIO_BATT_GET_BST")
+		b.setSource(FieldChargeRate, e.ChargeRate, Measured, "ACPIIO_BATT_GET_BST")
+		b.setSource(FieldCurrent, e.Current, Measured, "ACPIIO_BATT_GET_BST")
+		b.setSource(FieldVoltage, e.Voltage, Measured, "ACPIIO_BATT_GET_BST")
+		if opts.Attributes {
+			setUint32Attributes(b, "acpi_bst.", retptr[:16], "state", "rate", "cap", "volt")
+		}
 	} else {
 		e.State = err
 		e.ChargeRate = err
@@ -107,38 +198,62 @@ func systemGet(idx int) (*Battery, error) {
 		e.Voltage = err
 	}
 
+	*unit = idx
+	err = ioctl_(fd, 0x03, &retptr) // ACPIIO_BATT_GET_BATTINFO
+	if err == nil {
+		// Only known while discharging, -1 otherwise.
+		if min := int32(readUint32(retptr[4:8])); min >= 0 { // acpi_battinfo.min
+			b.FirmwareTimeToEmpty = time.Duration(min) * time.Minute
+			b.setSource(FieldFirmwareTimeToEmpty, nil, Measured, "ACPIIO_BATT_GET_BATTINFO")
+		}
+		if opts.Attributes {
+			// Signed, -1 meaning unknown.
+			for i, name := range []string{"cap", "min", "state", "rate"} {
+				b.setAttribute("acpi_battinfo."+name, strconv.Itoa(int(int32(readUint32(retptr[i*4:i*4+4])))))
+			}
+		}
+	} else {
+		e.Set(FieldFirmwareTimeToEmpty, err)
+	}
+	e.Set(FieldTemperature, ErrUnsupported)
+
 	if e.DesignVoltage != nil && e.Voltage == nil {
 		b.DesignVoltage, e.DesignVoltage = b.Voltage, nil
+		b.setSource(FieldDesignVoltage, nil, Copied, b.source(FieldVoltage))
 	}
 
 	if !mw {
-		if e.DesignVoltage == nil {
-			b.Design *= b.DesignVoltage
-		} else {
-			e.Design = e.DesignVoltage
-		}
-		if e.Voltage == nil {
-			b.Full *= b.Voltage
-			b.ChargeRate *= b.Voltage
-			b.Current *= b.Voltage
-		} else {
-			e.Full = e.Voltage
-			e.ChargeRate = e.Voltage
-			e.Current = e.Voltage
-		}
+		nativeCharge(b, &e, FieldDesign, FieldChargeDesign, opts)
+		nativeCharge(b, &e, FieldFull, FieldChargeFull, opts)
+		nativeCharge(b, &e, FieldChargeRate, FieldCurrentNow, opts)
+		nativeCharge(b, &e, FieldCurrent, FieldChargeNow, opts)
 	}
 
+	postprocess(b, &e, opts, readACAD(fd))
+
 	return b, e
 }
 
+func systemGet(ctx context.Context, idx int, opts Options) (*Battery, error) {
+	var b *Battery
+	var err error
+	if cerr := withContext(ctx, func() { b, err = getByIndex(idx, opts) }); cerr != nil {
+		return nil, cerr
+	}
+	return b, err
+}
+
 // There is no way to iterate over available batteries.
 // Therefore we assume here that if we were not able to retrieve
 // anything, it means we're done.
-func systemGetAll() ([]*Battery, error) {
+func systemGetAll(ctx context.Context, opts Options) ([]*Battery, error) {
 	var batteries []*Battery
 	var errors Errors
 	for i := 0; ; i++ {
-		b, err := systemGet(i)
+		b, err := systemGet(ctx, i, opts)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if perr, ok := err.(ErrPartial); ok && perr.noNil() {
 			break
 		}
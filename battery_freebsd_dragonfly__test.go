@@ -0,0 +1,55 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+//go:build freebsd || dragonfly
+
+package battery
+
+import (
+	"testing"
+)
+
+func TestBIXCycles(t *testing.T) {
+	cases := []struct {
+		in       [4]byte
+		countOut int
+		errOut   error
+	}{
+		{[4]byte{0x7b, 0, 0, 0}, 123, nil},
+		{[4]byte{0, 0, 0, 0}, 0, ErrUnknownValue},
+		{[4]byte{0xff, 0xff, 0xff, 0xff}, 0, ErrUnknownValue},
+	}
+
+	for i, c := range cases {
+		var bix [196]byte
+		copy(bix[32:36], c.in[:])
+		b := &Battery{}
+		e := ErrPartial{}
+		b.setCycleCount(&e, bixCycles(bix[:]), nil, "ACPIIO_BATT_GET_BIX")
+
+		if b.CycleCount != c.countOut {
+			t.Errorf("%d: %v != %v", i, b.CycleCount, c.countOut)
+		}
+		if err := e.Get(FieldCycleCount); err != c.errOut {
+			t.Errorf("%d: %v != %v", i, err, c.errOut)
+		}
+	}
+}
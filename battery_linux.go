@@ -22,25 +22,49 @@
 package battery
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
-const sysfs = "/sys/class/power_supply"
+const sysfs = "/sys"
 
-func readString(directory, filename string) (string, error) {
-	bytes, err := ioutil.ReadFile(filepath.Join(directory, filename))
+func powerSupplyDir(opts Options) string {
+	root := opts.SysfsRoot
+	if root == "" {
+		root = sysfs
+	}
+	return filepath.Join(root, "class", "power_supply")
+}
+
+// Reads from sysfs might block for a long time on slow embedded controllers,
+// so they are abandoned if ctx is done first.
+func readFile(ctx context.Context, path string) ([]byte, error) {
+	var bytes []byte
+	var err error
+	if cerr := withContext(ctx, func() { bytes, err = ioutil.ReadFile(path) }); cerr != nil {
+		return nil, cerr
+	}
+	return bytes, err
+}
+
+func readString(ctx context.Context, directory, filename string) (string, error) {
+	bytes, err := readFile(ctx, filepath.Join(directory, filename))
 	if err != nil {
 		return "", err
 	}
-	return string(bytes[:len(bytes)-1]), nil
+	return strings.TrimSuffix(string(bytes), "\n"), nil
 }
 
-func readInt(directory, filename string) (int64, error) {
-	str, err := readString(directory, filename)
+func readInt(ctx context.Context, directory, filename string) (int64, error) {
+	str, err := readString(ctx, directory, filename)
 	if err != nil {
 		return 0, err
 	}
@@ -51,8 +75,8 @@ func readInt(directory, filename string) (int64, error) {
 	return num, nil
 }
 
-func readFloat(directory, filename string) (float64, error) {
-	str, err := readString(directory, filename)
+func readFloat(ctx context.Context, directory, filename string) (float64, error) {
+	str, err := readString(ctx, directory, filename)
 	if err != nil {
 		return 0, err
 	}
@@ -63,89 +87,228 @@ func readFloat(directory, filename string) (float64, error) {
 	return num, nil
 }
 
-func readMilli(directory, filename string) (float64, error) {
-	val, err := readFloat(directory, filename)
+func readMilli(ctx context.Context, directory, filename string) (float64, error) {
+	val, err := readFloat(ctx, directory, filename)
 	if err != nil {
 		return 0, err
 	}
 	return val / 1000, nil // Convert micro->milli
 }
 
-func readAmp(directory, filename string, volts float64) (float64, error) {
-	val, err := readMilli(directory, filename)
+// readSeconds reads the first of the files that exists and is not 0,
+// which means there is no estimate, returning its path.
+// If there is no such file, an empty path and no error is returned.
+func readSeconds(ctx context.Context, directory string, filenames ...string) (time.Duration, string, error) {
+	for _, filename := range filenames {
+		secs, err := readInt(ctx, directory, filename)
+		if os.IsNotExist(err) || err == nil && secs == 0 {
+			continue
+		}
+		return time.Duration(secs) * time.Second, filepath.Join(directory, filename), err
+	}
+	return 0, "", nil
+}
+
+// readUevent stores POWER_SUPPLY_* key/value pairs from uevent file as attributes.
+func readUevent(ctx context.Context, directory string, b *Battery) {
+	uevent, err := readString(ctx, directory, "uevent")
 	if err != nil {
-		return 0, err
+		return
+	}
+	for _, line := range strings.Split(uevent, "\n") {
+		if i := strings.IndexByte(line, '='); i > 0 {
+			b.setAttribute(line[:i], line[i+1:])
+		}
+	}
+}
+
+// readHints reads external power status from the power supplies next to the battery
+// in directory, together with its charge threshold.
+func readHints(ctx context.Context, directory string) stateHints {
+	var h stateHints
+	if threshold, err := readFloat(ctx, directory, "charge_control_end_threshold"); err == nil {
+		h.threshold = threshold
+		h.thresholdSource = filepath.Join(directory, "charge_control_end_threshold")
+	}
+
+	dir := filepath.Dir(directory)
+	var files []os.FileInfo
+	var err error
+	if cerr := withContext(ctx, func() { files, err = ioutil.ReadDir(dir) }); cerr != nil || err != nil {
+		return h
+	}
+	for _, file := range files {
+		supply := filepath.Join(dir, file.Name())
+		if t, err := readString(ctx, supply, "type"); err != nil || (t != "Mains" && t != "USB") {
+			continue
+		}
+		online, err := readInt(ctx, supply, "online")
+		if err != nil {
+			continue
+		}
+		// Any supply being online is enough.
+		if h.online == nil || online == 1 {
+			o := online == 1
+			h.online, h.onlineSource = &o, filepath.Join(supply, "online")
+		}
+		if online == 1 {
+			break
+		}
+	}
+	return h
+}
+
+func isBattery(ctx context.Context, directory string) bool {
+	t, err := readString(ctx, directory, "type")
+	return err == nil && (t == "Battery" || t == "UPS")
+}
+
+// readKind tells the kind of the power supply in directory.
+// Batteries without scope are the ACPI ones, powering the system.
+func readKind(ctx context.Context, directory string) Kind {
+	if t, _ := readString(ctx, directory, "type"); t == "UPS" {
+		return KindUPS
+	}
+	scope, err := readString(ctx, directory, "scope")
+	switch {
+	case os.IsNotExist(err), scope == "System":
+		return KindSystem
+	case scope == "Device":
+		return KindPeripheral
 	}
-	return val * volts, nil
+	return KindUnknown
 }
 
-func isBattery(directory string) bool {
-	t, err := ioutil.ReadFile(filepath.Join(directory, "type"))
-	return err == nil && string(t) == "Battery\n"
+// readIdentity reads the values identifying the battery pack, except for the model.
+func readIdentity(ctx context.Context, directory string, b *Battery) {
+	b.Manufacturer, _ = readString(ctx, directory, "manufacturer")
+	serial, _ := readString(ctx, directory, "serial_number")
+	b.Serial = strings.TrimSpace(serial) // Often padded by the firmware.
+	if technology, err := readString(ctx, directory, "technology"); err == nil {
+		b.Chemistry = parseChemistry(technology)
+	}
+	if year, err := readInt(ctx, directory, "manufacture_year"); err == nil {
+		month, _ := readInt(ctx, directory, "manufacture_month")
+		day, _ := readInt(ctx, directory, "manufacture_day")
+		b.ManufactureDate = date(int(year), int(month), int(day))
+	}
+}
+
+// capacityLevels approximate the charge level (in %) for devices
+// that only report it coarsely, e.g. wireless mice.
+var capacityLevels = map[string]float64{
+	"Critical": 5,
+	"Low":      10,
+	"Normal":   55,
+	"High":     70,
+	"Full":     100,
 }
 
-func getBatteryFiles() ([]string, error) {
-	files, err := ioutil.ReadDir(sysfs)
+func getBatteryFiles(ctx context.Context, opts Options) ([]string, error) {
+	dir := powerSupplyDir(opts)
+
+	var files []os.FileInfo
+	var err error
+	if cerr := withContext(ctx, func() { files, err = ioutil.ReadDir(dir) }); cerr != nil {
+		return nil, cerr
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	var bFiles []string
 	for _, file := range files {
-		path := filepath.Join(sysfs, file.Name())
-		if isBattery(path) {
+		path := filepath.Join(dir, file.Name())
+		if isBattery(ctx, path) {
 			bFiles = append(bFiles, path)
 		}
 	}
+	sort.Slice(bFiles, func(i, j int) bool {
+		return naturalLess(bFiles[i], bFiles[j])
+	})
 	return bFiles, nil
 }
 
-func getByPath(directory string) (*Battery, error) {
-	b := &Battery{}
+func getByPath(ctx context.Context, directory string, opts Options) (*Battery, error) {
+	b := &Battery{ID: path.Base(directory), Kind: readKind(ctx, directory)}
 	e := ErrPartial{}
-	b.Capacity, e.Capacity = readFloat(directory, "capacity")
-	b.Current, e.Current = readMilli(directory, "energy_now")
-	b.Voltage, e.Voltage = readMilli(directory, "voltage_now")
+	file := func(filename string) string {
+		return filepath.Join(directory, filename)
+	}
+
+	b.Capacity, e.Capacity = readFloat(ctx, directory, "capacity")
+	b.setSource(FieldCapacity, e.Capacity, Measured, file("capacity"))
+	b.Current, e.Current = readMilli(ctx, directory, "energy_now")
+	b.setSource(FieldCurrent, e.Current, Measured, file("energy_now"))
+	b.Voltage, e.Voltage = readMilli(ctx, directory, "voltage_now")
 	b.Voltage /= 1000
+	b.setSource(FieldVoltage, e.Voltage, Measured, file("voltage_now"))
 
-	b.DesignVoltage, e.DesignVoltage = readMilli(directory, "voltage_max_design")
+	designVoltage := "voltage_max_design"
+	b.DesignVoltage, e.DesignVoltage = readMilli(ctx, directory, designVoltage)
 	if e.DesignVoltage != nil {
-		b.DesignVoltage, e.DesignVoltage = readMilli(directory, "voltage_min_design")
+		designVoltage = "voltage_min_design"
+		b.DesignVoltage, e.DesignVoltage = readMilli(ctx, directory, designVoltage)
 	}
+	b.DesignVoltage /= 1000
+	b.setSource(FieldDesignVoltage, e.DesignVoltage, Measured, file(designVoltage))
 	if e.DesignVoltage != nil && e.Voltage == nil {
 		b.DesignVoltage, e.DesignVoltage = b.Voltage, nil
+		b.setSource(FieldDesignVoltage, nil, Copied, b.source(FieldVoltage))
 	}
-	b.DesignVoltage /= 1000
 
 	if os.IsNotExist(e.Current) {
-		if e.DesignVoltage == nil {
-			b.Design, e.Design = readAmp(directory, "charge_full_design", b.DesignVoltage)
-		} else {
-			e.Design = e.DesignVoltage
+		readCharge := func(f Field, filename string) {
+			var err error
+			*b.float(f), err = readMilli(ctx, directory, filename)
+			e.Set(f, err)
+			b.setSource(f, err, Measured, file(filename))
 		}
-		if e.Voltage == nil {
-			b.Current, e.Current = readAmp(directory, "charge_now", b.Voltage)
-			b.Full, e.Full = readAmp(directory, "charge_full", b.Voltage)
-			b.ChargeRate, e.ChargeRate = readAmp(directory, "current_now", b.Voltage)
-		} else {
-			e.Current = e.Voltage
-			e.Full = e.Voltage
-			e.ChargeRate = e.Voltage
+		readCharge(FieldChargeDesign, "charge_full_design")
+		readCharge(FieldChargeNow, "charge_now")
+		readCharge(FieldChargeFull, "charge_full")
+		readCharge(FieldCurrentNow, "current_now")
+
+		chargeToEnergy(b, &e, FieldDesign, FieldChargeDesign, opts)
+		chargeToEnergy(b, &e, FieldCurrent, FieldChargeNow, opts)
+		chargeToEnergy(b, &e, FieldFull, FieldChargeFull, opts)
+		chargeToEnergy(b, &e, FieldChargeRate, FieldCurrentNow, opts)
+
+		if avg, err := readMilli(ctx, directory, "current_avg"); !os.IsNotExist(err) {
+			averageCurrent(b, &e, avg, err, file("current_avg"), opts)
 		}
 	} else {
-		b.Full, e.Full = readMilli(directory, "energy_full")
-		b.Design, e.Design = readMilli(directory, "energy_full_design")
-		b.ChargeRate, e.ChargeRate = readMilli(directory, "power_now")
+		b.Full, e.Full = readMilli(ctx, directory, "energy_full")
+		b.setSource(FieldFull, e.Full, Measured, file("energy_full"))
+		b.Design, e.Design = readMilli(ctx, directory, "energy_full_design")
+		b.setSource(FieldDesign, e.Design, Measured, file("energy_full_design"))
+		b.ChargeRate, e.ChargeRate = readMilli(ctx, directory, "power_now")
+		b.setSource(FieldChargeRate, e.ChargeRate, Measured, file("power_now"))
+
+		if avg, err := readMilli(ctx, directory, "power_avg"); !os.IsNotExist(err) {
+			b.AverageChargeRate = avg
+			e.Set(FieldAverageChargeRate, err)
+			b.setSource(FieldAverageChargeRate, err, Measured, file("power_avg"))
+		}
 	}
 
-	if e.Capacity != nil && e.Current == nil && e.Full != nil {
-		b.Capacity = b.Current / (b.Full * 100)
+	if e.Capacity != nil && e.Current == nil && e.Full == nil && b.Full > 0 {
+		b.Capacity = b.Current / b.Full * 100
 		e.Capacity = nil
+		b.setSource(FieldCapacity, nil, Derived, b.source(FieldCurrent), b.source(FieldFull))
+	}
+	if e.Capacity != nil {
+		if level, err := readString(ctx, directory, "capacity_level"); err == nil {
+			if capacity, ok := capacityLevels[level]; ok {
+				b.Capacity, e.Capacity = capacity, nil
+				b.setSource(FieldCapacity, nil, Derived, file("capacity_level"))
+			}
+		}
 	}
 
-	status, err := ioutil.ReadFile(filepath.Join(directory, "status"))
+	status, err := readString(ctx, directory, "status")
 	if err == nil {
-		status := string(status[:len(status)-1])
+		b.setSource(FieldState, nil, Measured, file("status"))
 		b.State.specific = status
 		switch status {
 		case "Unknown":
@@ -167,16 +330,55 @@ func getByPath(directory string) (*Battery, error) {
 		e.State = err
 	}
 
-	b.Name, err = readString(directory, "model_name")
-	if err != nil {
-		b.Name = path.Base(directory)
+	readEstimate := func(f Field, d *time.Duration, filenames ...string) {
+		var source string
+		*d, source, err = readSeconds(ctx, directory, filenames...)
+		e.Set(f, err)
+		if source != "" {
+			b.setSource(f, err, Measured, source)
+		}
+	}
+	readEstimate(FieldFirmwareTimeToEmpty, &b.FirmwareTimeToEmpty, "time_to_empty_now", "time_to_empty_avg")
+	readEstimate(FieldFirmwareTimeToFull, &b.FirmwareTimeToFull, "time_to_full_now", "time_to_full_avg")
+
+	if count, err := readInt(ctx, directory, "cycle_count"); os.IsNotExist(err) {
+		e.Set(FieldCycleCount, ErrValueNotFound)
+	} else {
+		b.setCycleCount(&e, count, err, file("cycle_count"))
+	}
+
+	if temp, err := readFloat(ctx, directory, "temp"); os.IsNotExist(err) {
+		e.Set(FieldTemperature, ErrValueNotFound)
+	} else {
+		b.Temperature = temp / 10 // Convert tenths of °C
+		e.Set(FieldTemperature, err)
+		b.setSource(FieldTemperature, err, Measured, file("temp"))
+	}
+
+	b.Model, _ = readString(ctx, directory, "model_name")
+	b.Name = b.Model
+	if b.Name == "" {
+		b.Name = b.ID
+	}
+	readIdentity(ctx, directory, b)
+
+	// Hints are only needed to infer the state the driver did not report,
+	// reading them would otherwise go through all of the power supplies.
+	var hints stateHints
+	if b.State.Raw == Unknown || b.State.Raw == Undefined || ignoresState(b, opts, runtime.GOOS) {
+		hints = readHints(ctx, directory)
+	}
+	postprocess(b, &e, opts, hints)
+
+	if opts.Attributes {
+		readUevent(ctx, directory, b)
 	}
 
 	return b, e
 }
 
-func systemGet(idx int) (*Battery, error) {
-	bFiles, err := getBatteryFiles()
+func systemGet(ctx context.Context, idx int, opts Options) (*Battery, error) {
+	bFiles, err := getBatteryFiles(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -184,11 +386,11 @@ func systemGet(idx int) (*Battery, error) {
 	if idx >= len(bFiles) {
 		return nil, ErrNotFound
 	}
-	return getByPath(bFiles[idx])
+	return getByPath(ctx, bFiles[idx], opts)
 }
 
-func systemGetAll() ([]*Battery, error) {
-	bFiles, err := getBatteryFiles()
+func systemGetAll(ctx context.Context, opts Options) ([]*Battery, error) {
+	bFiles, err := getBatteryFiles(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -196,7 +398,7 @@ func systemGetAll() ([]*Battery, error) {
 	batteries := make([]*Battery, len(bFiles))
 	errors := make(Errors, len(bFiles))
 	for i, bFile := range bFiles {
-		battery, err := getByPath(bFile)
+		battery, err := getByPath(ctx, bFile, opts)
 		batteries[i] = battery
 		errors[i] = err
 	}
@@ -0,0 +1,357 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSystemGetAllLinux(t *testing.T) {
+	// Divide at runtime, the same way the backend does.
+	div := func(a, b float64) float64 { return a / b }
+
+	cases := []struct {
+		root         string
+		batteriesOut []*Battery
+		errorsOut    string
+		originsOut   map[Field]Origin
+	}{{
+		"testdata/energy",
+		[]*Battery{{
+			ID:            "BAT0",
+			Kind:          KindSystem,
+			Name:          "5B10W13930",
+			State:         State{Raw: Discharging, specific: "Discharging"},
+			Capacity:      75,
+			Current:       45000,
+			Full:          60000,
+			Design:        62000,
+			ChargeRate:    12000,
+			Voltage:       11.8,
+			DesignVoltage: 11.4,
+			NetPower:      -12000,
+			ChargeNow:     div(45000, 11.8),
+			ChargeFull:    div(60000, 11.8),
+			ChargeDesign:  div(62000, 11.4),
+			CurrentNow:    div(12000, 11.8),
+
+			FirmwareTimeToEmpty: 3*time.Hour + 45*time.Minute,
+			AverageChargeRate:   11000,
+			CycleCount:          123,
+			Temperature:         31.2,
+
+			Manufacturer:    "LGC",
+			Model:           "5B10W13930",
+			Serial:          "1234",
+			Chemistry:       ChemistryLiPoly,
+			ManufactureDate: time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC),
+		}},
+		"[{}]",
+		map[Field]Origin{
+			FieldState: Measured, FieldCapacity: Measured, FieldCurrent: Measured, FieldFull: Measured,
+			FieldDesign: Measured, FieldChargeRate: Measured, FieldVoltage: Measured, FieldDesignVoltage: Measured,
+			FieldNetPower: Derived, FieldChargeNow: Derived, FieldChargeFull: Derived, FieldChargeDesign: Derived,
+			FieldCurrentNow: Derived, FieldFirmwareTimeToEmpty: Measured, FieldAverageChargeRate: Measured,
+			FieldCycleCount: Measured, FieldTemperature: Measured,
+		},
+	}, {
+		"testdata/charge",
+		[]*Battery{{
+			ID:            "BAT1",
+			Kind:          KindSystem,
+			Name:          "01AV431",
+			State:         State{Raw: Charging, specific: "Charging"},
+			Capacity:      75,
+			Current:       36000,
+			Full:          48000,
+			Design:        52500,
+			ChargeRate:    18000,
+			Voltage:       12,
+			DesignVoltage: 12.5,
+			NetPower:      18000,
+			ChargeNow:     3000,
+			ChargeFull:    4000,
+			ChargeDesign:  4200,
+			CurrentNow:    1500,
+
+			FirmwareTimeToFull: 40 * time.Minute,
+			AverageChargeRate:  16800,
+
+			Model:     "01AV431",
+			Chemistry: ChemistryLiIon,
+		}},
+		"[{CycleCount:Unknown value received Temperature:Value not found}]",
+		map[Field]Origin{
+			FieldState: Measured, FieldCapacity: Measured, FieldCurrent: Derived, FieldFull: Derived,
+			FieldDesign: Derived, FieldChargeRate: Derived, FieldVoltage: Measured, FieldDesignVoltage: Measured,
+			FieldNetPower: Derived, FieldChargeNow: Measured, FieldChargeFull: Measured, FieldChargeDesign: Measured,
+			FieldCurrentNow: Measured, FieldFirmwareTimeToFull: Measured, FieldAverageChargeRate: Derived,
+		},
+	}, {
+		"testdata/signed",
+		[]*Battery{{
+			ID:            "BAT0",
+			Kind:          KindSystem,
+			Name:          "BAT0",
+			State:         State{Raw: Discharging, specific: "Unknown", Inferred: true},
+			Capacity:      50,
+			Current:       24000,
+			Full:          48000,
+			Design:        48000,
+			ChargeRate:    18000,
+			Voltage:       12,
+			DesignVoltage: 12,
+			NetPower:      -18000,
+			ChargeNow:     2000,
+			ChargeFull:    4000,
+			ChargeDesign:  4000,
+			CurrentNow:    1500,
+
+			AverageChargeRate: 19200,
+		}},
+		"[{CycleCount:Value not found Temperature:Value not found}]",
+		map[Field]Origin{
+			FieldState: Derived, FieldCapacity: Measured, FieldCurrent: Derived, FieldFull: Derived,
+			FieldDesign: Derived, FieldChargeRate: Derived, FieldVoltage: Measured, FieldDesignVoltage: Measured,
+			FieldNetPower: Derived, FieldChargeNow: Measured, FieldChargeFull: Measured, FieldChargeDesign: Measured,
+			FieldCurrentNow: Measured, FieldAverageChargeRate: Derived,
+		},
+	}, {
+		"testdata/threshold",
+		[]*Battery{{
+			ID:            "BAT0",
+			Kind:          KindSystem,
+			Name:          "BAT0",
+			State:         State{Raw: Idle, specific: "Unknown", Inferred: true},
+			Capacity:      80,
+			Current:       48000,
+			Full:          60000,
+			Design:        60000,
+			ChargeRate:    500,
+			Voltage:       12,
+			DesignVoltage: 12,
+			NetPower:      500,
+			ChargeNow:     4000,
+			ChargeFull:    5000,
+			ChargeDesign:  5000,
+			CurrentNow:    div(500, 12),
+		}},
+		"[{CycleCount:Value not found Temperature:Value not found}]",
+		map[Field]Origin{
+			FieldState: Derived, FieldCapacity: Measured, FieldCurrent: Measured, FieldFull: Measured,
+			FieldDesign: Measured, FieldChargeRate: Measured, FieldVoltage: Measured, FieldDesignVoltage: Measured,
+			FieldNetPower: Derived, FieldChargeNow: Derived, FieldChargeFull: Derived, FieldChargeDesign: Derived,
+			FieldCurrentNow: Derived,
+		},
+	}, {
+		"testdata/novoltage",
+		[]*Battery{{
+			ID:            "BAT0",
+			Kind:          KindSystem,
+			Name:          "BAT0",
+			State:         State{Raw: Discharging, specific: "Discharging"},
+			Capacity:      75,
+			Current:       30000,
+			Full:          40000,
+			Design:        44400,
+			ChargeRate:    10000,
+			DesignVoltage: 11.1,
+			NetPower:      -10000,
+			ChargeDesign:  div(44400, 11.1),
+			Warnings:      []Warning{{WarnZeroVoltage, FieldVoltage, 0, false}},
+		}},
+		"[{" +
+			"ChargeNow:Unknown value received " +
+			"ChargeFull:Unknown value received " +
+			"CurrentNow:Unknown value received " +
+			"CycleCount:Value not found " +
+			"Temperature:Value not found" +
+			"}]",
+		map[Field]Origin{
+			FieldState: Measured, FieldCapacity: Derived, FieldCurrent: Measured, FieldFull: Measured,
+			FieldDesign: Measured, FieldChargeRate: Measured, FieldVoltage: Measured, FieldDesignVoltage: Measured,
+			FieldNetPower: Derived, FieldChargeDesign: Derived,
+		},
+	}, {
+		"testdata/broken",
+		[]*Battery{{
+			ID:            "BAT0",
+			Kind:          KindSystem,
+			Name:          "BAT0",
+			State:         State{Raw: Unknown, specific: "Unknown"},
+			DesignVoltage: 11.1,
+			ChargeNow:     2000,
+			ChargeFull:    4000,
+			Quirks:        []string{"zero-design-charge"},
+		}},
+		"[{" +
+			"Capacity:strconv.ParseFloat: parsing \"\": invalid syntax " +
+			"Current:open testdata/broken/class/power_supply/BAT0/voltage_now: no such file or directory " +
+			"Full:open testdata/broken/class/power_supply/BAT0/voltage_now: no such file or directory " +
+			"Design:Unknown value received " +
+			"ChargeRate:open testdata/broken/class/power_supply/BAT0/voltage_now: no such file or directory " +
+			"Voltage:open testdata/broken/class/power_supply/BAT0/voltage_now: no such file or directory " +
+			"NetPower:open testdata/broken/class/power_supply/BAT0/voltage_now: no such file or directory " +
+			"ChargeDesign:Unknown value received " +
+			"CurrentNow:open testdata/broken/class/power_supply/BAT0/current_now: no such file or directory " +
+			"FirmwareTimeToEmpty:strconv.ParseInt: parsing \"garbage\": invalid syntax " +
+			"CycleCount:Value not found " +
+			"Temperature:Value not found" +
+			"}]",
+		map[Field]Origin{
+			FieldState: Measured, FieldDesignVoltage: Measured,
+			FieldChargeNow: Measured, FieldChargeFull: Measured,
+		},
+	}}
+
+	for i, c := range cases {
+		batteries, err := systemGetAll(context.Background(), Options{SysfsRoot: c.root})
+
+		origins := map[Field]Origin{}
+		for f, p := range batteries[0].Provenance {
+			origins[f] = p.Origin
+			if !strings.HasPrefix(p.Source, c.root) {
+				t.Errorf("%d: %s source %s outside of %s", i, f, p.Source, c.root)
+			}
+		}
+		batteries[0].Provenance = nil
+		if !reflect.DeepEqual(origins, c.originsOut) {
+			t.Errorf("%d: %v != %v", i, origins, c.originsOut)
+		}
+		if !reflect.DeepEqual(batteries, c.batteriesOut) {
+			t.Errorf("%d: %v != %v", i, batteries, c.batteriesOut)
+		}
+		if err.Error() != c.errorsOut {
+			t.Errorf("%d: %v != %v", i, err, c.errorsOut)
+		}
+	}
+}
+
+func TestGetAllWithOptionsLinux(t *testing.T) {
+	batteries, err := GetAllWithOptions(context.Background(), Options{SysfsRoot: "testdata/energy"})
+	if err != nil {
+		t.Fatalf("%v != nil", err)
+	}
+	if len(batteries) != 1 || batteries[0].Current != 45000 {
+		t.Errorf("%v", batteries)
+	}
+
+	_, err = GetAllWithOptions(context.Background(), Options{SysfsRoot: "testdata/nonexistent"})
+	if _, ok := err.(ErrFatal); !ok {
+		t.Errorf("%v is not ErrFatal", err)
+	}
+}
+
+func TestAttributesLinux(t *testing.T) {
+	batteries, _ := systemGetAll(context.Background(), Options{SysfsRoot: "testdata/energy"})
+	if batteries[0].Attributes != nil {
+		t.Errorf("%v != nil", batteries[0].Attributes)
+	}
+
+	batteries, _ = systemGetAll(context.Background(), Options{SysfsRoot: "testdata/energy", Attributes: true})
+	attributes := batteries[0].Attributes
+	if len(attributes) != 13 {
+		t.Errorf("%d != 13", len(attributes))
+	}
+	if attributes["POWER_SUPPLY_TECHNOLOGY"] != "Li-poly" {
+		t.Errorf("%v != Li-poly", attributes["POWER_SUPPLY_TECHNOLOGY"])
+	}
+
+	batteries, _ = systemGetAll(context.Background(), Options{SysfsRoot: "testdata/charge", Attributes: true})
+	if batteries[0].Attributes != nil {
+		t.Errorf("%v != nil", batteries[0].Attributes)
+	}
+}
+
+func TestKindLinux(t *testing.T) {
+	batteries, _ := systemGetAll(context.Background(), Options{SysfsRoot: "testdata/peripheral"})
+	b := batteries[0]
+	if b.Kind != KindPeripheral {
+		t.Errorf("%v != %v", b.Kind, KindPeripheral)
+	}
+	if b.Capacity != 55 || b.source(FieldCapacity) != "testdata/peripheral/class/power_supply/hidpp_battery_0/capacity_level" {
+		t.Errorf("%v (%s) != 55 (capacity_level)", b.Capacity, b.source(FieldCapacity))
+	}
+	if b.Manufacturer != "Logitech" || b.Name != "MX Master 3" {
+		t.Errorf("%s %s != Logitech MX Master 3", b.Manufacturer, b.Name)
+	}
+}
+
+func TestConversionLinux(t *testing.T) {
+	cases := []struct {
+		opts                                Options
+		current, full, design, chargeRate   float64
+		chargeNow, chargeFull, chargeDesign float64
+	}{
+		{Options{}, 36000, 48000, 52500, 18000, 3000, 4000, 4200},
+		{Options{Conversion: ConvertDesign}, 37500, 50000, 52500, 18750, 3000, 4000, 4200},
+		{Options{Conversion: ConvertNominal, NominalVoltage: 10}, 30000, 40000, 42000, 15000, 3000, 4000, 4200},
+		{Options{Conversion: ConvertNominal}, 37500, 50000, 52500, 18750, 3000, 4000, 4200},
+		{Options{SysfsRoot: "testdata/energy", Conversion: ConvertNominal, NominalVoltage: 10}, 45000, 60000, 62000, 12000, 4500, 6000, 6200},
+	}
+
+	for i, c := range cases {
+		if c.opts.SysfsRoot == "" {
+			c.opts.SysfsRoot = "testdata/charge"
+		}
+		batteries, _ := systemGetAll(context.Background(), c.opts)
+		b := batteries[0]
+
+		got := []float64{b.Current, b.Full, b.Design, b.ChargeRate, b.ChargeNow, b.ChargeFull, b.ChargeDesign}
+		want := []float64{c.current, c.full, c.design, c.chargeRate, c.chargeNow, c.chargeFull, c.chargeDesign}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d: %v != %v", i, got, want)
+		}
+	}
+}
+
+func TestQuirkScaleLinux(t *testing.T) {
+	quirks := []Quirk{{Name: "double-design", Scale: map[Field]float64{FieldDesign: 2}}}
+	batteries, _ := systemGetAll(context.Background(), Options{SysfsRoot: "testdata/charge", Quirks: quirks})
+
+	if batteries[0].Design != 105000 {
+		t.Errorf("%v != %v", batteries[0].Design, 105000)
+	}
+	if batteries[0].Full != 48000 {
+		t.Errorf("%v != %v", batteries[0].Full, 48000)
+	}
+	if want := []string{"double-design"}; !reflect.DeepEqual(batteries[0].Quirks, want) {
+		t.Errorf("%v != %v", batteries[0].Quirks, want)
+	}
+}
+
+func TestQuirkIgnoreStateLinux(t *testing.T) {
+	quirks := []Quirk{{Name: "bogus-state", Ignore: []Field{FieldState}}}
+	batteries, _ := systemGetAll(context.Background(), Options{SysfsRoot: "testdata/energy", Quirks: quirks})
+
+	if batteries[0].State.Raw != Charging {
+		t.Errorf("%v != %v", batteries[0].State.Raw, Charging)
+	}
+	want := "testdata/energy/class/power_supply/BAT0/status, testdata/energy/class/power_supply/AC/online, testdata/energy/class/power_supply/BAT0/power_now"
+	if got := batteries[0].source(FieldState); got != want {
+		t.Errorf("%v != %v", got, want)
+	}
+}
@@ -22,9 +22,8 @@
 package battery
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"math"
 	"sort"
 	"strings"
 	"unsafe"
@@ -50,6 +49,8 @@ type prop []values
 
 type props map[string]prop
 
+type rawProps map[string][]map[string]interface{}
+
 func readBytes(ptr unsafe.Pointer, length uint64) []byte {
 	buf := make([]byte, length-1)
 	var i uint64
@@ -59,30 +60,37 @@ func readBytes(ptr unsafe.Pointer, length uint64) []byte {
 	return buf
 }
 
-func readProps() (props, error) {
+func readProps(attributes bool) (props, rawProps, error) {
 	fd, err := unix.Open("/dev/sysmon", unix.O_RDONLY, 0777)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer unix.Close(fd)
 
 	var retptr plistref
 
 	if err = ioctl(fd, 0, 'E', unsafe.Sizeof(retptr), unsafe.Pointer(&retptr)); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	bytes := readBytes(retptr.pref_plist, retptr.pref_len)
 
 	var props props
 	if _, err = plist.Unmarshal(bytes, &props); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return props, nil
+
+	var raw rawProps
+	if attributes {
+		if _, err = plist.Unmarshal(bytes, &raw); err != nil {
+			return nil, nil, err
+		}
+	}
+	return props, raw, nil
 }
 
 func handleValue(val values, div float64, res *float64, amps *[]string) error {
 	if val.State == "invalid" || val.State == "unknown" {
-		return errors.New("Unknown value received")
+		return ErrUnknownValue
 	}
 
 	*res = float64(val.CurValue) / div
@@ -94,50 +102,52 @@ func handleValue(val values, div float64, res *float64, amps *[]string) error {
 	return nil
 }
 
-func deriveState(cr1, cr2 error, current float64, max int) (AgnosticState, error) {
+// deriveState returns Unknown for contradicting values,
+// to be inferred from the other ones.
+func deriveState(cr1, cr2 error, current float64, max int) AgnosticState {
 	if cr1 == nil && cr2 != nil {
-		return Charging, nil
+		return Charging
 	}
 	if cr1 != nil && cr2 == nil {
-		return Discharging, nil
+		return Discharging
 	}
 	if cr1 != nil && cr2 != nil && current == float64(max)/1000 {
-		return Full, nil
+		return Full
 	}
-	return Unknown, errors.New("Contradicting values received")
+	return Unknown
 }
 
-func handleVoltage(amps []string, b *Battery, e *ErrPartial) {
+// readHints reads external power status from the AC adapters.
+func readHints(props props) stateHints {
+	for key, prop := range props {
+		if !strings.HasPrefix(key, "acpiacad") {
+			continue
+		}
+		for _, val := range prop {
+			if val.Description == "connected" && val.State == "valid" {
+				return onlineHints(val.CurValue != 0, key+":connected")
+			}
+		}
+	}
+	return stateHints{}
+}
+
+func handleVoltage(amps []string, b *Battery, e *ErrPartial, opts Options) {
 	if e.DesignVoltage != nil && e.Voltage == nil {
 		b.DesignVoltage, e.DesignVoltage = b.Voltage, nil
+		b.setSource(FieldDesignVoltage, nil, Copied, b.source(FieldVoltage))
 	}
 
 	for _, val := range amps {
 		switch val {
 		case "design cap":
-			if e.DesignVoltage == nil {
-				b.Design *= b.DesignVoltage
-			} else {
-				e.Design = e.DesignVoltage
-			}
+			nativeCharge(b, e, FieldDesign, FieldChargeDesign, opts)
 		case "last full cap":
-			if e.Voltage == nil {
-				b.Full *= b.Voltage
-			} else {
-				e.Full = e.Voltage
-			}
+			nativeCharge(b, e, FieldFull, FieldChargeFull, opts)
 		case "charge":
-			if e.Voltage == nil {
-				b.Current *= b.Voltage
-			} else {
-				e.Current = e.Voltage
-			}
+			nativeCharge(b, e, FieldCurrent, FieldChargeNow, opts)
 		case "charge rate", "discharge rate":
-			if e.Voltage == nil {
-				b.ChargeRate *= b.Voltage
-			} else {
-				e.ChargeRate = e.Voltage
-			}
+			nativeCharge(b, e, FieldChargeRate, FieldCurrentNow, opts)
 		}
 	}
 }
@@ -150,49 +160,94 @@ func sortFilterProps(props props) []string {
 		}
 		keys = append(keys, key)
 	}
-	sort.Strings(keys)
+	sort.Slice(keys, func(i, j int) bool {
+		return naturalLess(keys[i], keys[j])
+	})
 	return keys
 }
 
-func convertBattery(prop prop) (*Battery, error) {
-	b := &Battery{}
+func convertBattery(id string, prop prop, raw []map[string]interface{}, hints stateHints, opts Options) (*Battery, error) {
+	b := &Battery{ID: id, Kind: KindSystem}
 	e := ErrPartial{}
 
+	for _, entry := range raw {
+		prefix := ""
+		if desc, ok := entry["description"].(string); ok {
+			prefix = desc + "."
+		}
+		b.setAttributes(prefix, entry)
+	}
+
 	amps := []string{}
 	var cr1, cr2 error
 	var maxCharge int
 
+	e.Set(FieldTemperature, ErrValueNotFound)
 	for _, val := range prop {
+		source := id + ":" + val.Description
+		if val.Type == "Temperature" {
+			err := handleValue(val, 1000000, &b.Temperature, nil)
+			if err == nil {
+				b.Temperature = celsius(b.Temperature) // Reported in uK.
+			}
+			e.Set(FieldTemperature, err)
+			b.setSource(FieldTemperature, err, Measured, source)
+			continue
+		}
 		switch val.Description {
 		case "voltage":
 			e.Voltage = handleValue(val, 1000000, &b.Voltage, nil)
+			b.setSource(FieldVoltage, e.Voltage, Measured, source)
 		case "design voltage":
 			e.DesignVoltage = handleValue(val, 1000000, &b.DesignVoltage, nil)
+			b.setSource(FieldDesignVoltage, e.DesignVoltage, Measured, source)
 		case "design cap":
 			e.Design = handleValue(val, 1000, &b.Design, &amps)
+			b.setSource(FieldDesign, e.Design, Measured, source)
 		case "last full cap":
 			e.Full = handleValue(val, 1000, &b.Full, &amps)
+			b.setSource(FieldFull, e.Full, Measured, source)
 		case "charge":
 			e.Current = handleValue(val, 1000, &b.Current, &amps)
+			b.setSource(FieldCurrent, e.Current, Measured, source)
 			maxCharge = val.MaxValue
 		case "charge rate":
-			cr1 = handleValue(val, 1000, &b.ChargeRate, &amps)
+			// Only one of the rates is valid at a time.
+			if cr1 = handleValue(val, 1000, &b.ChargeRate, &amps); cr1 == nil {
+				b.setSource(FieldChargeRate, nil, Measured, source)
+			}
 		case "discharge rate":
-			cr2 = handleValue(val, 1000, &b.ChargeRate, &amps)
-			b.ChargeRate = math.Abs(b.ChargeRate)
+			if cr2 = handleValue(val, 1000, &b.ChargeRate, &amps); cr2 == nil {
+				b.setSource(FieldChargeRate, nil, Measured, source)
+			}
 		}
 	}
 
-	b.State.Raw, e.State = deriveState(cr1, cr2, b.Current, maxCharge)
-	b.State.specific = fmt.Sprintf("cr1: %v, cr2: %v")
+	b.State.Raw = deriveState(cr1, cr2, b.Current, maxCharge)
+	b.State.specific = fmt.Sprintf("cr1: %v, cr2: %v", cr1, cr2)
+	b.setSource(FieldState, nil, Derived, id+":charge rate", id+":discharge rate")
 
-	handleVoltage(amps, b, &e)
+	handleVoltage(amps, b, &e, opts)
+	e.Set(FieldCycleCount, ErrUnsupported)
+	postprocess(b, &e, opts, hints)
 
 	return b, e
 }
 
-func systemGet(idx int) (*Battery, error) {
-	props, err := readProps()
+// Ioctl on sysmon might block on misbehaving drivers,
+// so it is abandoned if ctx is done first.
+func readPropsContext(ctx context.Context, opts Options) (props, rawProps, error) {
+	var p props
+	var r rawProps
+	var err error
+	if cerr := withContext(ctx, func() { p, r, err = readProps(opts.Attributes) }); cerr != nil {
+		return nil, nil, cerr
+	}
+	return p, r, err
+}
+
+func systemGet(ctx context.Context, idx int, opts Options) (*Battery, error) {
+	props, raw, err := readPropsContext(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -201,20 +256,21 @@ func systemGet(idx int) (*Battery, error) {
 	if idx >= len(keys) {
 		return nil, ErrNotFound
 	}
-	return convertBattery(props[keys[idx]])
+	return convertBattery(keys[idx], props[keys[idx]], raw[keys[idx]], readHints(props), opts)
 }
 
-func systemGetAll() ([]*Battery, error) {
-	props, err := readProps()
+func systemGetAll(ctx context.Context, opts Options) ([]*Battery, error) {
+	props, raw, err := readPropsContext(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	keys := sortFilterProps(props)
+	hints := readHints(props)
 	batteries := make([]*Battery, len(keys))
 	errors := make(Errors, len(keys))
 	for i, key := range keys {
-		batteries[i], errors[i] = convertBattery(props[key])
+		batteries[i], errors[i] = convertBattery(key, props[key], raw[key], hints, opts)
 	}
 
 	return batteries, errors
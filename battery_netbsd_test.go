@@ -0,0 +1,123 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTemperatureNetBSD(t *testing.T) {
+	cases := []struct {
+		in       prop
+		out      float64
+		errorOut error
+	}{
+		{prop{{Description: "temperature", Type: "Temperature", CurValue: 308150000, State: "valid"}}, 35, nil},
+		{prop{{Description: "temperature", Type: "Temperature", State: "invalid"}}, 0, ErrUnknownValue},
+		{prop{}, 0, ErrValueNotFound},
+	}
+
+	for i, c := range cases {
+		b, err := convertBattery("acpibat0", c.in, nil, stateHints{}, Options{})
+
+		if math.Abs(b.Temperature-c.out) > 1e-9 {
+			t.Errorf("%d: %v != %v", i, b.Temperature, c.out)
+		}
+		if e := err.(ErrPartial).Get(FieldTemperature); e != c.errorOut {
+			t.Errorf("%d: %v != %v", i, e, c.errorOut)
+		}
+	}
+}
+
+func TestDeriveStateNetBSD(t *testing.T) {
+	cases := []struct {
+		cr1, cr2 error
+		current  float64
+		max      int
+		out      AgnosticState
+	}{
+		{nil, ErrUnknownValue, 10, 20000, Charging},
+		{ErrUnknownValue, nil, 10, 20000, Discharging},
+		{ErrUnknownValue, ErrUnknownValue, 20, 20000, Full},
+		{ErrUnknownValue, ErrUnknownValue, 10, 20000, Unknown},
+		{nil, nil, 10, 20000, Unknown},
+	}
+
+	for i, c := range cases {
+		state := deriveState(c.cr1, c.cr2, c.current, c.max)
+
+		if state != c.out {
+			t.Errorf("%d: %v != %v", i, state, c.out)
+		}
+	}
+}
+
+func TestChargeRateNetBSD(t *testing.T) {
+	cases := []struct {
+		in     prop
+		rate   float64
+		source string
+	}{
+		{prop{
+			{Description: "charge rate", Type: "Watts", CurValue: 12000000, State: "valid"},
+			{Description: "discharge rate", Type: "Watts", State: "invalid"},
+		}, 12000, "acpibat0:charge rate"},
+		{prop{
+			{Description: "charge rate", Type: "Watts", State: "invalid"},
+			{Description: "discharge rate", Type: "Watts", CurValue: 9000000, State: "valid"},
+		}, 9000, "acpibat0:discharge rate"},
+	}
+
+	for i, c := range cases {
+		b, _ := convertBattery("acpibat0", c.in, nil, stateHints{}, Options{})
+
+		if b.ChargeRate != c.rate {
+			t.Errorf("%d: %v != %v", i, b.ChargeRate, c.rate)
+		}
+		if source := b.source(FieldChargeRate); source != c.source {
+			t.Errorf("%d: %v != %v", i, source, c.source)
+		}
+	}
+}
+
+func TestReadHintsNetBSD(t *testing.T) {
+	online, offline := true, false
+	cases := []struct {
+		in  props
+		out stateHints
+	}{
+		{props{"acpiacad0": {{Description: "connected", CurValue: 1, State: "valid"}}}, stateHints{online: &online, onlineSource: "acpiacad0:connected"}},
+		{props{"acpiacad0": {{Description: "connected", CurValue: 0, State: "valid"}}}, stateHints{online: &offline, onlineSource: "acpiacad0:connected"}},
+		{props{"acpiacad0": {{Description: "connected", State: "invalid"}}}, stateHints{}},
+		{props{"acpibat0": {{Description: "charge", CurValue: 1, State: "valid"}}}, stateHints{}},
+	}
+
+	for i, c := range cases {
+		hints := readHints(c.in)
+
+		if !reflect.DeepEqual(hints, c.out) {
+			t.Errorf("%d: %v != %v", i, hints, c.out)
+		}
+	}
+}
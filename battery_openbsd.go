@@ -23,8 +23,11 @@ package battery
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
@@ -43,11 +46,11 @@ func sysctl(mib []int32, out unsafe.Pointer, n uintptr) unix.Errno {
 	return e
 }
 
-var errValueNotFound = fmt.Errorf("Value not found")
-
-func setErr(err *error, newErr error) {
-	if *err == errValueNotFound {
-		*err = newErr
+func setErrs(err *ErrPartial, newErr error) {
+	for _, f := range err.Failed() {
+		if err.Get(f) == ErrValueNotFound {
+			err.Set(f, newErr)
+		}
 	}
 }
 
@@ -59,10 +62,20 @@ var sensorW = [4]int32{
 }
 
 const (
-	sensorA  = 6 // SENSOR_AMPS (uA)
-	sensorAH = 8 // SENSOR_AMPHOUR (uAh)
+	sensorTemp = 0 // SENSOR_TEMP (uK)
+	sensorA    = 6 // SENSOR_AMPS (uA)
+	sensorAH   = 8 // SENSOR_AMPHOUR (uAh)
 )
 
+// Names of the sensor types, as used by sysctl(8), indexed by enum sensor_type.
+var sensorTypes = [...]string{
+	"temp", "fan", "volt", "acvolt", "resistance", "power",
+	"current", "watthour", "amphour", "indicator", "raw",
+	"percent", "illuminance", "drive", "timedelta", "humidity",
+	"frequency", "angle", "distance", "pressure", "acceleration",
+	"velocity", "energy",
+}
+
 type sensorStatus int32
 
 const (
@@ -85,25 +98,38 @@ type sensor struct {
 
 func (s *sensor) readValue(div float64) (float64, error) {
 	if s.status == unknown {
-		return 0, fmt.Errorf("Unknown value received")
+		return 0, ErrUnknownValue
 	}
 
 	return float64(s.value) / div, nil
 }
 
-func (s *sensor) handleA(factor float64, field *float64, fieldErr *error) {
-	*field, *fieldErr = s.readValue(1000)
-	if *fieldErr == nil {
-		*field *= factor
-	}
+type apmPowerInfo struct {
+	batteryState uint8
+	acState      uint8
+	batteryLife  uint8
+	spare1       uint8
+	minutesLeft  uint32
+	spare2       [6]uint32
 }
 
-func (s *sensor) handleAH(factor float64, factorErr error, field *float64, fieldErr *error) {
-	if factorErr != nil {
-		*fieldErr = factorErr
-		return
+// readAPM returns power information from APM, for the system as a whole.
+// False is returned if APM is not available.
+func readAPM() (apmPowerInfo, bool) {
+	var info apmPowerInfo
+	fd, err := unix.Open("/dev/apm", unix.O_RDONLY, 0)
+	if err != nil {
+		return info, false
 	}
-	s.handleA(factor, field, fieldErr)
+	defer unix.Close(fd)
+
+	_, _, errno := unix.Syscall(
+		unix.SYS_IOCTL,
+		uintptr(fd),
+		0x40204103, // APM_IOC_GETPOWER
+		uintptr(unsafe.Pointer(&info)),
+	)
+	return info, errno == 0
 }
 
 type sensordev struct {
@@ -113,48 +139,47 @@ type sensordev struct {
 	sensors_count int32
 }
 
-func (sd *sensordev) get() (*Battery, error) {
-	var battery Battery
+func (sd *sensordev) get(opts Options) (*Battery, error) {
+	xname := string(sd.xname[:bytes.IndexByte(sd.xname[:], 0)])
+	battery := Battery{ID: xname, Kind: KindSystem}
 	err := ErrPartial{
-		Design:        errValueNotFound,
-		Full:          errValueNotFound,
-		Current:       errValueNotFound,
-		ChargeRate:    errValueNotFound,
-		State:         errValueNotFound,
-		Voltage:       errValueNotFound,
-		DesignVoltage: errValueNotFound,
+		Design:        ErrValueNotFound,
+		Full:          ErrValueNotFound,
+		Current:       ErrValueNotFound,
+		ChargeRate:    ErrValueNotFound,
+		State:         ErrValueNotFound,
+		Voltage:       ErrValueNotFound,
+		DesignVoltage: ErrValueNotFound,
 	}
 
 	mib := []int32{unix.CTL_HW, 11, sd.num, 0, 0}
 	var s sensor
 
-	iter := func(maxnumtIdx int32, cb func(string)) {
+	iter := func(maxnumtIdx int32, cb func(desc, name string)) {
 		mib[3] = maxnumtIdx
 
 		for i := int32(0); i < sd.maxnumt[maxnumtIdx]; i++ {
 			mib[4] = i
 
 			if errno := sysctl(mib, unsafe.Pointer(&s), unsafe.Sizeof(s)); errno != 0 {
-				setErr(&err.Design, errno)
-				setErr(&err.Full, errno)
-				setErr(&err.Current, errno)
-				setErr(&err.ChargeRate, errno)
-				setErr(&err.Voltage, errno)
-				setErr(&err.DesignVoltage, errno)
-				setErr(&err.State, errno)
+				setErrs(&err, errno)
 			}
 
 			// Convert 0-terminated C-string to a Go string
-			cb(string(s.desc[:bytes.IndexByte(s.desc[:], 0)]))
+			cb(
+				string(s.desc[:bytes.IndexByte(s.desc[:], 0)]),
+				fmt.Sprintf("hw.sensors.%s.%s%d", xname, sensorTypes[maxnumtIdx], i),
+			)
 		}
 	}
 
 	for _, w := range sensorW {
 		mib[3] = w
 
-		iter(w, func(desc string) {
+		iter(w, func(desc, name string) {
 			if strings.HasPrefix(desc, "battery ") {
 				battery.State.specific, err.State = desc, nil
+				battery.setSource(FieldState, nil, Measured, name)
 
 				switch desc[8:] {
 				case "unknown":
@@ -177,51 +202,105 @@ func (sd *sensordev) get() (*Battery, error) {
 			switch desc {
 			case "rate":
 				battery.ChargeRate, err.ChargeRate = s.readValue(1000)
+				battery.setSource(FieldChargeRate, err.ChargeRate, Measured, name)
 			case "design capacity":
 				battery.Design, err.Design = s.readValue(1000)
+				battery.setSource(FieldDesign, err.Design, Measured, name)
 			case "last full capacity":
 				battery.Full, err.Full = s.readValue(1000)
+				battery.setSource(FieldFull, err.Full, Measured, name)
 			case "remaining capacity":
 				battery.Current, err.Current = s.readValue(1000)
+				battery.setSource(FieldCurrent, err.Current, Measured, name)
 			case "current voltage":
 				battery.Voltage, err.Voltage = s.readValue(1000_000)
+				battery.setSource(FieldVoltage, err.Voltage, Measured, name)
 			case "voltage":
 				battery.DesignVoltage, err.DesignVoltage = s.readValue(1000_000)
+				battery.setSource(FieldDesignVoltage, err.DesignVoltage, Measured, name)
 			}
 		})
 	}
 
 	if err.DesignVoltage != nil && err.Voltage == nil {
 		battery.DesignVoltage, err.DesignVoltage = battery.Voltage, nil
+		battery.setSource(FieldDesignVoltage, nil, Copied, battery.source(FieldVoltage))
 	}
-	if err.ChargeRate == errValueNotFound {
-		if err.Voltage == nil {
-			iter(sensorA, func(desc string) {
-				if desc == "rate" {
-					s.handleA(battery.Voltage, &battery.ChargeRate, &err.ChargeRate)
-				}
-			})
-		} else {
-			err.ChargeRate = err.Voltage
-		}
+	// Charge based values, reported in uAh and uA.
+	readCharge := func(f Field, name string) {
+		var e error
+		*battery.float(f), e = s.readValue(1000)
+		err.Set(f, e)
+		battery.setSource(f, e, Measured, name)
+	}
+	if err.ChargeRate == ErrValueNotFound {
+		iter(sensorA, func(desc, name string) {
+			if desc == "rate" {
+				readCharge(FieldCurrentNow, name)
+				chargeToEnergy(&battery, &err, FieldChargeRate, FieldCurrentNow, opts)
+			}
+		})
 	}
-	if err.Design == errValueNotFound || err.Full == errValueNotFound || err.Current == errValueNotFound {
-		iter(sensorAH, func(desc string) {
+	if err.Design == ErrValueNotFound || err.Full == ErrValueNotFound || err.Current == ErrValueNotFound {
+		iter(sensorAH, func(desc, name string) {
 			switch desc {
 			case "design capacity":
-				s.handleAH(battery.DesignVoltage, err.DesignVoltage, &battery.Design, &err.Design)
+				readCharge(FieldChargeDesign, name)
+				chargeToEnergy(&battery, &err, FieldDesign, FieldChargeDesign, opts)
 			case "last full capacity":
-				s.handleAH(battery.Voltage, err.Voltage, &battery.Full, &err.Full)
+				readCharge(FieldChargeFull, name)
+				chargeToEnergy(&battery, &err, FieldFull, FieldChargeFull, opts)
 			case "remaining capacity":
-				s.handleAH(battery.Voltage, err.Voltage, &battery.Current, &err.Current)
+				readCharge(FieldChargeNow, name)
+				chargeToEnergy(&battery, &err, FieldCurrent, FieldChargeNow, opts)
 			}
 		})
 	}
 
+	err.Set(FieldTemperature, ErrValueNotFound)
+	iter(sensorTemp, func(desc, name string) {
+		var e error
+		if battery.Temperature, e = s.readValue(1000_000); e == nil {
+			battery.Temperature = celsius(battery.Temperature)
+		}
+		err.Set(FieldTemperature, e)
+		battery.setSource(FieldTemperature, e, Measured, name)
+	})
+
+	// APM values cover all the batteries together.
+	var hints stateHints
+	if info, ok := readAPM(); ok {
+		if info.minutesLeft != 0xffffffff { // No estimate, e.g. when not discharging.
+			battery.FirmwareTimeToEmpty = time.Duration(info.minutesLeft) * time.Minute
+			battery.setSource(FieldFirmwareTimeToEmpty, nil, Measured, "APM_IOC_GETPOWER")
+		}
+		switch info.acState {
+		case 0: // APM_AC_OFF
+			hints = onlineHints(false, "APM_IOC_GETPOWER")
+		case 1: // APM_AC_ON
+			hints = onlineHints(true, "APM_IOC_GETPOWER")
+		}
+	}
+
+	err.Set(FieldCycleCount, ErrUnsupported)
+
+	postprocess(&battery, &err, opts, hints)
+
+	if opts.Attributes {
+		for t := range sd.maxnumt {
+			iter(int32(t), func(desc, name string) {
+				battery.setAttribute(name, strconv.FormatInt(s.value, 10))
+				if desc != "" {
+					battery.setAttribute(name+".desc", desc)
+				}
+			})
+		}
+	}
+
 	return &battery, err
 }
 
-func getBatteryAtMIBIndex(i int32) (*Battery, int32, error) {
+func getBatteryAtMIBIndex(i int32, opts Options) (*Battery, int32, error) {
 	mib := []int32{
 		unix.CTL_HW,
 		11, // HW_SENSORS
@@ -239,17 +318,35 @@ func getBatteryAtMIBIndex(i int32) (*Battery, int32, error) {
 			continue
 		}
 		if bytes.HasPrefix(sd.xname[:], []byte("acpibat")) {
-			battery, err := sd.get()
+			battery, err := sd.get(opts)
 			return battery, i + 1, err
 		}
 	}
 	return nil, i, nil
 }
 
-func systemGet(idx int) (*Battery, error) {
+// Sysctl calls might block on misbehaving drivers,
+// so they are abandoned if ctx is done first.
+func getBatteryAtMIBIndexContext(ctx context.Context, i int32, opts Options) (*Battery, int32, error) {
+	var battery *Battery
+	var iNext int32
+	var err error
+	if cerr := withContext(ctx, func() { battery, iNext, err = getBatteryAtMIBIndex(i, opts) }); cerr != nil {
+		return nil, i, cerr
+	}
+	return battery, iNext, err
+}
+
+func systemGet(ctx context.Context, idx int, opts Options) (*Battery, error) {
 	var i int32
 	for idxCurr := 0; ; idxCurr++ {
-		battery, iNext, err := getBatteryAtMIBIndex(i)
+		battery, iNext, err := getBatteryAtMIBIndexContext(ctx, i, opts)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if battery == nil && err == nil { // no more batteries
+			break
+		}
 		// If this is the index we look for, grab it.
 		// Otherwise just move on, regardless of errors etc.
 		if idxCurr == idx {
@@ -262,13 +359,16 @@ func systemGet(idx int) (*Battery, error) {
 	return nil, ErrNotFound
 }
 
-func systemGetAll() ([]*Battery, error) {
+func systemGetAll(ctx context.Context, opts Options) ([]*Battery, error) {
 	var batteries []*Battery
 	var errors Errors
 
 	var i int32
 	for {
-		battery, iNext, err := getBatteryAtMIBIndex(i)
+		battery, iNext, err := getBatteryAtMIBIndexContext(ctx, i, opts)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if battery == nil && err == nil { // no more batteries
 			break
 		}
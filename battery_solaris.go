@@ -24,22 +24,22 @@ package battery
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"math"
 	"os/exec"
 	"strconv"
+	"strings"
 )
 
-var errValueNotFound = fmt.Errorf("Value not found")
-
 func readFloat(val string) (float64, error) {
 	num, err := strconv.ParseFloat(val, 64)
 	if err != nil {
 		return 0, err
 	}
 	if num == math.MaxUint32 {
-		return 0, fmt.Errorf("Unknown value received")
+		return 0, ErrUnknownValue
 	}
 	return num, nil
 }
@@ -81,29 +81,16 @@ type batteryReader struct {
 	li     int
 	lline  []byte
 	e      ErrPartial
+
+	hints stateHints
+	opts  Options
 }
 
 func (r *batteryReader) setErrParse(n int) {
-	if r.e.Design == errValueNotFound {
-		r.e.Design = errParse(n)
-	}
-	if r.e.Full == errValueNotFound {
-		r.e.Full = errParse(n)
-	}
-	if r.e.Current == errValueNotFound {
-		r.e.Current = errParse(n)
-	}
-	if r.e.ChargeRate == errValueNotFound {
-		r.e.ChargeRate = errParse(n)
-	}
-	if r.e.State == errValueNotFound {
-		r.e.State = errParse(n)
-	}
-	if r.e.Voltage == errValueNotFound {
-		r.e.Voltage = errParse(n)
-	}
-	if r.e.DesignVoltage == errValueNotFound {
-		r.e.DesignVoltage = errParse(n)
+	for _, f := range r.e.Failed() {
+		if r.e.Get(f) == ErrValueNotFound {
+			r.e.Set(f, errParse(n))
+		}
 	}
 }
 
@@ -139,8 +126,8 @@ func (r *batteryReader) readValue() (string, string, int) {
 	return string(values[0]), string(values[1]), 0
 }
 
-func (r *batteryReader) readBattery() (*Battery, bool, bool) {
-	b := &Battery{}
+func (r *batteryReader) readBattery(id string) (*Battery, bool, bool) {
+	b := &Battery{ID: id, Kind: KindSystem}
 	var exists, amps bool
 
 	for r.cmdout.Scan() {
@@ -155,24 +142,44 @@ func (r *batteryReader) readBattery() (*Battery, bool, bool) {
 			continue
 		}
 
+		if r.opts.Attributes {
+			b.setAttribute(name, value)
+		}
+
+		source := id + ":" + name
 		switch name {
 		case "bif_design_cap":
 			b.Design, r.e.Design = readFloat(value)
+			b.setSource(FieldDesign, r.e.Design, Measured, source)
 		case "bif_last_cap":
 			b.Full, r.e.Full = readFloat(value)
+			b.setSource(FieldFull, r.e.Full, Measured, source)
 		case "bif_unit":
 			amps = value != "0"
 		case "bif_voltage":
 			b.DesignVoltage, r.e.DesignVoltage = readVoltage(value)
+			b.setSource(FieldDesignVoltage, r.e.DesignVoltage, Measured, source)
+		case "bif_model":
+			b.Model = value
+		case "bif_serial":
+			b.Serial = value
+		case "bif_type":
+			b.Chemistry = parseChemistry(value)
+		case "bif_oem_info":
+			b.Manufacturer = value
 		case "bst_voltage":
 			b.Voltage, r.e.Voltage = readVoltage(value)
+			b.setSource(FieldVoltage, r.e.Voltage, Measured, source)
 		case "bst_rem_cap":
 			b.Current, r.e.Current = readFloat(value)
+			b.setSource(FieldCurrent, r.e.Current, Measured, source)
 		case "bst_rate":
 			b.ChargeRate, r.e.ChargeRate = readFloat(value)
+			b.setSource(FieldChargeRate, r.e.ChargeRate, Measured, source)
 		case "bst_state":
 			b.State.Raw, r.e.State = readState(value)
 			b.State.specific = value
+			b.setSource(FieldState, r.e.State, Measured, source)
 		}
 	}
 
@@ -181,16 +188,16 @@ func (r *batteryReader) readBattery() (*Battery, bool, bool) {
 
 func (r *batteryReader) next() (*Battery, error) {
 	r.e = ErrPartial{
-		Design:        errValueNotFound,
-		Full:          errValueNotFound,
-		Current:       errValueNotFound,
-		ChargeRate:    errValueNotFound,
-		State:         errValueNotFound,
-		Voltage:       errValueNotFound,
-		DesignVoltage: errValueNotFound,
+		Design:        ErrValueNotFound,
+		Full:          ErrValueNotFound,
+		Current:       ErrValueNotFound,
+		ChargeRate:    ErrValueNotFound,
+		State:         ErrValueNotFound,
+		Voltage:       ErrValueNotFound,
+		DesignVoltage: ErrValueNotFound,
 	}
 
-	b, amps, exists := r.readBattery()
+	b, amps, exists := r.readBattery(fmt.Sprintf("acpi_drv:%d", r.li))
 
 	if !exists {
 		return nil, io.EOF
@@ -198,53 +205,79 @@ func (r *batteryReader) next() (*Battery, error) {
 
 	if r.e.DesignVoltage != nil && r.e.Voltage == nil {
 		b.DesignVoltage, r.e.DesignVoltage = b.Voltage, nil
+		b.setSource(FieldDesignVoltage, nil, Copied, b.source(FieldVoltage))
 	}
 
 	if amps {
-		if r.e.DesignVoltage == nil {
-			b.Design *= b.DesignVoltage
-		} else {
-			r.e.Design = r.e.DesignVoltage
-		}
-		if r.e.Voltage == nil {
-			b.Full *= b.Voltage
-			b.Current *= b.Voltage
-			b.ChargeRate *= b.Voltage
-		} else {
-			r.e.Full = r.e.Voltage
-			r.e.Current = r.e.Voltage
-			r.e.ChargeRate = r.e.Voltage
-		}
+		nativeCharge(b, &r.e, FieldDesign, FieldChargeDesign, r.opts)
+		nativeCharge(b, &r.e, FieldFull, FieldChargeFull, r.opts)
+		nativeCharge(b, &r.e, FieldCurrent, FieldChargeNow, r.opts)
+		nativeCharge(b, &r.e, FieldChargeRate, FieldCurrentNow, r.opts)
 	}
 
-	if b.State.Raw == Unknown && r.e.Current == nil && r.e.Full == nil && b.Current >= b.Full {
-		b.State.Raw = Full
-		b.State.specific = fmt.Sprintf("current >= full")
-	}
+	r.e.Set(FieldCycleCount, ErrUnsupported)
+	r.e.Set(FieldTemperature, ErrUnsupported)
+
+	postprocess(b, &r.e, r.opts, r.hints)
 
 	return b, r.e
 }
 
-func newBatteryReader() (*batteryReader, error) {
-	out, err := exec.Command("kstat", "-p", "-m", "acpi_drv", "-n", "battery B*").Output()
+// readHints reads external power status, reported as "AC" or "battery".
+func readHints(ctx context.Context) stateHints {
+	out, err := exec.CommandContext(ctx, "kstat", "-p", "-m", "acpi_drv", "-n", "power", "-s", "power").Output()
+	if err != nil {
+		return stateHints{}
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return stateHints{}
+	}
+	switch fields[1] {
+	case "AC":
+		return onlineHints(true, fields[0])
+	case "battery":
+		return onlineHints(false, fields[0])
+	}
+	return stateHints{}
+}
+
+func newBatteryReader(ctx context.Context, opts Options) (*batteryReader, error) {
+	out, err := exec.CommandContext(ctx, "kstat", "-p", "-m", "acpi_drv", "-n", "battery B*").Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &batteryReader{cmdout: bufio.NewScanner(bytes.NewReader(out))}, nil
+	return &batteryReader{
+		cmdout: bufio.NewScanner(bytes.NewReader(out)),
+		hints:  readHints(ctx),
+		opts:   opts,
+	}, nil
 }
 
-func systemGet(idx int) (*Battery, error) {
-	br, err := newBatteryReader()
+func systemGet(ctx context.Context, idx int, opts Options) (*Battery, error) {
+	br, err := newBatteryReader(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return br.next()
+	for i := 0; i < idx; i++ {
+		if _, err := br.next(); err == io.EOF {
+			return nil, ErrNotFound
+		}
+	}
+	b, err := br.next()
+	if err == io.EOF {
+		return nil, ErrNotFound
+	}
+	return b, err
 }
 
-func systemGetAll() ([]*Battery, error) {
-	br, err := newBatteryReader()
+func systemGetAll(ctx context.Context, opts Options) ([]*Battery, error) {
+	br, err := newBatteryReader(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
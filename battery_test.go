@@ -46,8 +46,11 @@ func TestGet(t *testing.T) {
 		&Battery{Full: 3}, ErrPartial{},
 		&Battery{Full: 3}, nil,
 	}, {
-		nil, ErrPartial{State: fmt.Errorf("t3"), Current: fmt.Errorf("t4"), Full: fmt.Errorf("t5"), Design: fmt.Errorf("t6"), ChargeRate: fmt.Errorf("t7"), Voltage: fmt.Errorf("t8"), DesignVoltage: fmt.Errorf("t9")},
+		nil, ErrPartial{State: fmt.Errorf("t3"), Capacity: fmt.Errorf("c1"), Current: fmt.Errorf("t4"), Full: fmt.Errorf("t5"), Design: fmt.Errorf("t6"), ChargeRate: fmt.Errorf("t7"), Voltage: fmt.Errorf("t8"), DesignVoltage: fmt.Errorf("t9")},
 		nil, ErrFatal{ErrAllNotNil},
+	}, {
+		&Battery{Capacity: 50}, ErrPartial{State: fmt.Errorf("t3"), Current: fmt.Errorf("t4"), Full: fmt.Errorf("t5"), Design: fmt.Errorf("t6"), ChargeRate: fmt.Errorf("t7"), Voltage: fmt.Errorf("t8"), DesignVoltage: fmt.Errorf("t9")},
+		&Battery{Capacity: 50}, ErrPartial{State: fmt.Errorf("t3"), Current: fmt.Errorf("t4"), Full: fmt.Errorf("t5"), Design: fmt.Errorf("t6"), ChargeRate: fmt.Errorf("t7"), Voltage: fmt.Errorf("t8"), DesignVoltage: fmt.Errorf("t9")},
 	}}
 
 	for i, c := range cases {
@@ -82,13 +85,13 @@ func TestGetAll(t *testing.T) {
 		[]*Battery{{Full: 2}, {Full: 3}}, Errors{ErrPartial{}, ErrPartial{}},
 		[]*Battery{{Full: 2}, {Full: 3}}, nil,
 	}, {
-		[]*Battery{{Full: 4}, {Full: 5}}, Errors{ErrPartial{State: fmt.Errorf("t2"), Current: fmt.Errorf("t3"), Full: fmt.Errorf("t4"), Design: fmt.Errorf("t5"), ChargeRate: fmt.Errorf("t6"), Voltage: fmt.Errorf("t101"), DesignVoltage: fmt.Errorf("t102")}, ErrPartial{State: fmt.Errorf("t7"), Current: fmt.Errorf("t8"), Full: fmt.Errorf("t9"), Design: fmt.Errorf("t10"), ChargeRate: fmt.Errorf("t11"), Voltage: fmt.Errorf("t103"), DesignVoltage: fmt.Errorf("t104")}},
+		[]*Battery{{Full: 4}, {Full: 5}}, Errors{ErrPartial{State: fmt.Errorf("t2"), Capacity: fmt.Errorf("c2"), Current: fmt.Errorf("t3"), Full: fmt.Errorf("t4"), Design: fmt.Errorf("t5"), ChargeRate: fmt.Errorf("t6"), Voltage: fmt.Errorf("t101"), DesignVoltage: fmt.Errorf("t102")}, ErrPartial{State: fmt.Errorf("t7"), Capacity: fmt.Errorf("c3"), Current: fmt.Errorf("t8"), Full: fmt.Errorf("t9"), Design: fmt.Errorf("t10"), ChargeRate: fmt.Errorf("t11"), Voltage: fmt.Errorf("t103"), DesignVoltage: fmt.Errorf("t104")}},
 		nil, ErrFatal{ErrAllNotNil},
 	}, {
 		[]*Battery{{Full: 6}, {Full: 7}}, Errors{ErrPartial{State: fmt.Errorf("t12")}, fmt.Errorf("t13")},
 		[]*Battery{{Full: 6}, {Full: 7}}, Errors{ErrPartial{State: fmt.Errorf("t12")}, ErrFatal{fmt.Errorf("t13")}},
 	}, {
-		[]*Battery{{}, {Full: 8}}, Errors{ErrPartial{State: fmt.Errorf("t14"), Current: fmt.Errorf("t15"), Full: fmt.Errorf("t16"), Design: fmt.Errorf("t17"), ChargeRate: fmt.Errorf("t18"), Voltage: fmt.Errorf("t105"), DesignVoltage: fmt.Errorf("t106")}, nil},
+		[]*Battery{{}, {Full: 8}}, Errors{ErrPartial{State: fmt.Errorf("t14"), Capacity: fmt.Errorf("c4"), Current: fmt.Errorf("t15"), Full: fmt.Errorf("t16"), Design: fmt.Errorf("t17"), ChargeRate: fmt.Errorf("t18"), Voltage: fmt.Errorf("t105"), DesignVoltage: fmt.Errorf("t106")}, nil},
 		[]*Battery{{}, {Full: 8}}, Errors{ErrFatal{ErrAllNotNil}, nil},
 	}, {
 		[]*Battery{{Full: 9}, {Full: 10}}, Errors{ErrPartial{}, fmt.Errorf("t19")},
@@ -110,6 +113,66 @@ func TestGetAll(t *testing.T) {
 	}
 }
 
+func TestGetByID(t *testing.T) {
+	cases := []struct {
+		batteriesIn []*Battery
+		errorsIn    error
+		id          string
+		batteryOut  *Battery
+		errorOut    error
+	}{{
+		[]*Battery{{ID: "BAT0", Full: 1}, {ID: "BAT1", Full: 2}}, Errors{nil, nil},
+		"BAT1", &Battery{ID: "BAT1", Full: 2}, nil,
+	}, {
+		[]*Battery{{ID: "BAT0", Full: 3}, {ID: "BAT1", Full: 4}}, Errors{ErrPartial{Full: fmt.Errorf("t1")}, nil},
+		"BAT0", &Battery{ID: "BAT0", Full: 3}, ErrPartial{Full: fmt.Errorf("t1")},
+	}, {
+		[]*Battery{nil, {ID: "BAT1", Full: 5}}, Errors{fmt.Errorf("t2"), nil},
+		"BAT2", nil, ErrFatal{ErrNotFound},
+	}, {
+		nil, fmt.Errorf("t3"),
+		"BAT0", nil, ErrFatal{fmt.Errorf("t3")},
+	}}
+
+	for i, c := range cases {
+		f := func() ([]*Battery, error) {
+			return c.batteriesIn, c.errorsIn
+		}
+		battery, err := getByID(f, c.id)
+
+		if !reflect.DeepEqual(battery, c.batteryOut) {
+			t.Errorf("%d: %v != %v", i, battery, c.batteryOut)
+		}
+		if !reflect.DeepEqual(err, c.errorOut) {
+			t.Errorf("%d: %v != %v", i, err, c.errorOut)
+		}
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	cases := []struct {
+		a, b string
+		less bool
+	}{
+		{"BAT0", "BAT1", true},
+		{"BAT2", "BAT10", true},
+		{"BAT10", "BAT2", false},
+		{"BAT1", "BAT1", false},
+		{"BAT01", "BAT2", true},
+		{"BAT", "BAT0", true},
+		{"BAT9", "CMB0", true},
+		{"acpibat10", "acpibat9", false},
+	}
+
+	for i, c := range cases {
+		less := naturalLess(c.a, c.b)
+
+		if less != c.less {
+			t.Errorf("%d: %v != %v", i, less, c.less)
+		}
+	}
+}
+
 func ExampleGetAll() {
 	batteries, err := GetAll()
 	if err != nil {
@@ -183,3 +246,33 @@ func ExampleGet_errors() {
 		fmt.Println("Got current battery capacity")
 	}
 }
+
+func TestSetCycleCount(t *testing.T) {
+	cases := []struct {
+		count    int64
+		errIn    error
+		countOut int
+		errOut   error
+	}{
+		{123, nil, 123, nil},
+		{0, nil, 0, ErrUnknownValue},
+		{-1, nil, 0, ErrUnknownValue},
+		{0, ErrValueNotFound, 0, ErrValueNotFound},
+	}
+
+	for i, c := range cases {
+		b := &Battery{}
+		e := ErrPartial{}
+		b.setCycleCount(&e, c.count, c.errIn, "cycle_count")
+
+		if b.CycleCount != c.countOut {
+			t.Errorf("%d: %v != %v", i, b.CycleCount, c.countOut)
+		}
+		if err := e.Get(FieldCycleCount); err != c.errOut {
+			t.Errorf("%d: %v != %v", i, err, c.errOut)
+		}
+		if has := b.Provenance[FieldCycleCount].Source != ""; has != (c.errOut == nil) {
+			t.Errorf("%d: %v != %v", i, has, c.errOut == nil)
+		}
+	}
+}
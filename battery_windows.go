@@ -22,10 +22,14 @@
 package battery
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"math"
+	"reflect"
+	"strconv"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
@@ -50,6 +54,12 @@ type batteryInformation struct {
 	CycleCount          uint32
 }
 
+type batteryManufactureDate struct {
+	Day   uint8
+	Month uint8
+	Year  uint16
+}
+
 type batteryWaitStatus struct {
 	BatteryTag   uint32
 	Timeout      uint32
@@ -90,14 +100,14 @@ func int32ToFloat64(num int32) (float64, error) {
 	// There is something wrong with this constant, but
 	// it appears to work so far...
 	if num == -0x80000000 { // BATTERY_UNKNOWN_RATE
-		return 0, errors.New("Unknown value received")
+		return 0, ErrUnknownValue
 	}
-	return math.Abs(float64(num)), nil
+	return float64(num), nil
 }
 
 func uint32ToFloat64(num uint32) (float64, error) {
 	if num == 0xffffffff { // BATTERY_UNKNOWN_CAPACITY
-		return 0, errors.New("Unknown value received")
+		return 0, ErrUnknownValue
 	}
 	return float64(num), nil
 }
@@ -130,6 +140,62 @@ var setupDiEnumDeviceInterfaces = setupapi.NewProc("SetupDiEnumDeviceInterfaces"
 var setupDiGetDeviceInterfaceDetailW = setupapi.NewProc("SetupDiGetDeviceInterfaceDetailW")
 var setupDiDestroyDeviceInfoList = setupapi.NewProc("SetupDiDestroyDeviceInfoList")
 
+// queryString returns string information of the given level, empty if not available.
+func queryString(handle windows.Handle, bqi batteryQueryInformation, level int32) string {
+	var buf [128]uint16
+	var dwOut uint32
+	bqi.InformationLevel = level
+	err := windows.DeviceIoControl(
+		handle,
+		2703428, // IOCTL_BATTERY_QUERY_INFORMATION
+		(*byte)(unsafe.Pointer(&bqi)),
+		uint32(unsafe.Sizeof(bqi)),
+		(*byte)(unsafe.Pointer(&buf[0])),
+		uint32(unsafe.Sizeof(buf)),
+		&dwOut,
+		nil,
+	)
+	if err != nil {
+		return ""
+	}
+	return windows.UTF16ToString(buf[:])
+}
+
+// setStructAttributes stores members of struct v as attributes, named with prefix.
+func setStructAttributes(b *Battery, prefix string, v interface{}) {
+	rv := reflect.ValueOf(v)
+	for i := 0; i < rv.NumField(); i++ {
+		name := rv.Type().Field(i).Name
+		if name == "Reserved" {
+			continue
+		}
+		value := rv.Field(i).Interface()
+		if chars, ok := value.([4]uint8); ok {
+			value = string(bytes.TrimRight(chars[:], "\x00 "))
+		}
+		b.setAttribute(prefix+name, fmt.Sprint(value))
+	}
+}
+
+// readTemperature converts BatteryTemperature, in tenths of K, 0 meaning unknown.
+func readTemperature(temperature uint32) (float64, error) {
+	if temperature == 0 {
+		return 0, ErrUnknownValue
+	}
+	return celsius(float64(temperature) / 10), nil
+}
+
+func readKind(capabilities uint32) Kind {
+	switch {
+	case capabilities&0x80000000 == 0: // BATTERY_SYSTEM_BATTERY
+		return KindPeripheral
+	case capabilities&0x20000000 != 0: // BATTERY_IS_SHORT_TERM
+		return KindUPS
+	default:
+		return KindSystem
+	}
+}
+
 func readState(powerState uint32) AgnosticState {
 	switch {
 	case powerState&0x00000004 != 0:
@@ -145,7 +211,7 @@ func readState(powerState uint32) AgnosticState {
 	}
 }
 
-func systemGet(idx int) (*Battery, error) {
+func getByIndex(idx int, opts Options) (*Battery, error) {
 	hdev, err := setupDiSetup(
 		setupDiGetClassDevsW,
 		4,
@@ -252,7 +318,7 @@ func systemGet(idx int) (*Battery, error) {
 		return nil, errors.New("BatteryTag not returned")
 	}
 
-	b := &Battery{}
+	b := &Battery{ID: windows.UTF16PtrToString(devicePath)}
 	e := ErrPartial{}
 
 	var bi batteryInformation
@@ -267,13 +333,104 @@ func systemGet(idx int) (*Battery, error) {
 		nil,
 	)
 	if err == nil {
+		b.Kind = readKind(bi.Capabilities)
 		b.Full = float64(bi.FullChargedCapacity)
+		b.setSource(FieldFull, nil, Measured, "IOCTL_BATTERY_QUERY_INFORMATION")
 		b.Design = float64(bi.DesignedCapacity)
+		b.setSource(FieldDesign, nil, Measured, "IOCTL_BATTERY_QUERY_INFORMATION")
+		b.setCycleCount(&e, int64(bi.CycleCount), nil, "IOCTL_BATTERY_QUERY_INFORMATION")
+		b.Chemistry = parseChemistry(string(bytes.TrimRight(bi.Chemistry[:], "\x00 ")))
+		if opts.Attributes {
+			setStructAttributes(b, "BATTERY_INFORMATION.", bi)
+		}
 	} else {
 		e.Full = err
 		e.Design = err
+		e.Set(FieldCycleCount, err)
+	}
+
+	for _, q := range []struct {
+		level int32
+		name  string
+		value *string
+	}{
+		{4, "BatteryDeviceName", &b.Model},
+		{6, "BatteryManufactureName", &b.Manufacturer},
+		{8, "BatterySerialNumber", &b.Serial},
+	} {
+		*q.value = queryString(handle, bqi, q.level)
+		if opts.Attributes && *q.value != "" {
+			b.setAttribute(q.name, *q.value)
+		}
+	}
+	var bmd batteryManufactureDate
+	bqi.InformationLevel = 5 // BatteryManufactureDate
+	err = windows.DeviceIoControl(
+		handle,
+		2703428, // IOCTL_BATTERY_QUERY_INFORMATION
+		(*byte)(unsafe.Pointer(&bqi)),
+		uint32(unsafe.Sizeof(bqi)),
+		(*byte)(unsafe.Pointer(&bmd)),
+		uint32(unsafe.Sizeof(bmd)),
+		&dwOut,
+		nil,
+	)
+	if err == nil {
+		b.ManufactureDate = date(int(bmd.Year), int(bmd.Month), int(bmd.Day))
+		if opts.Attributes {
+			setStructAttributes(b, "BATTERY_MANUFACTURE_DATE.", bmd)
+		}
+	}
+
+	var estimatedTime uint32
+	bqi.InformationLevel = 3 // BatteryEstimatedTime
+	err = windows.DeviceIoControl(
+		handle,
+		2703428, // IOCTL_BATTERY_QUERY_INFORMATION
+		(*byte)(unsafe.Pointer(&bqi)),
+		uint32(unsafe.Sizeof(bqi)),
+		(*byte)(unsafe.Pointer(&estimatedTime)),
+		uint32(unsafe.Sizeof(estimatedTime)),
+		&dwOut,
+		nil,
+	)
+	if err == nil {
+		if opts.Attributes {
+			b.setAttribute("BatteryEstimatedTime", strconv.FormatUint(uint64(estimatedTime), 10))
+		}
+		if estimatedTime != 0xffffffff { // BATTERY_UNKNOWN_TIME
+			b.FirmwareTimeToEmpty = time.Duration(estimatedTime) * time.Second
+			b.setSource(FieldFirmwareTimeToEmpty, nil, Measured, "BatteryEstimatedTime")
+		}
+	} else {
+		e.Set(FieldFirmwareTimeToEmpty, err)
 	}
 
+	var temperature uint32
+	bqi.InformationLevel = 2 // BatteryTemperature
+	err = windows.DeviceIoControl(
+		handle,
+		2703428, // IOCTL_BATTERY_QUERY_INFORMATION
+		(*byte)(unsafe.Pointer(&bqi)),
+		uint32(unsafe.Sizeof(bqi)),
+		(*byte)(unsafe.Pointer(&temperature)),
+		uint32(unsafe.Sizeof(temperature)),
+		&dwOut,
+		nil,
+	)
+	if err == nil && opts.Attributes {
+		b.setAttribute("BatteryTemperature", strconv.FormatUint(uint64(temperature), 10))
+	}
+	if err == windows.ERROR_INVALID_FUNCTION { // Not reported by the battery.
+		err = ErrUnsupported
+	}
+	if err == nil {
+		b.Temperature, err = readTemperature(temperature)
+	}
+	e.Set(FieldTemperature, err)
+	b.setSource(FieldTemperature, err, Measured, "BatteryTemperature")
+
+	var hints stateHints
 	bws := batteryWaitStatus{BatteryTag: bqi.BatteryTag}
 	var bs batteryStatus
 	err = windows.DeviceIoControl(
@@ -288,11 +445,19 @@ func systemGet(idx int) (*Battery, error) {
 	)
 	if err == nil {
 		b.Current, e.Current = uint32ToFloat64(bs.Capacity)
+		b.setSource(FieldCurrent, e.Current, Measured, "IOCTL_BATTERY_QUERY_STATUS")
 		b.ChargeRate, e.ChargeRate = int32ToFloat64(bs.Rate)
+		b.setSource(FieldChargeRate, e.ChargeRate, Measured, "IOCTL_BATTERY_QUERY_STATUS")
 		b.Voltage, e.Voltage = uint32ToFloat64(bs.Voltage)
 		b.Voltage /= 1000
+		b.setSource(FieldVoltage, e.Voltage, Measured, "IOCTL_BATTERY_QUERY_STATUS")
 		b.State.Raw = readState(bs.PowerState)
 		b.State.specific = fmt.Sprintf("%x", bs.PowerState)
+		b.setSource(FieldState, nil, Measured, "IOCTL_BATTERY_QUERY_STATUS")
+		hints = onlineHints(bs.PowerState&0x00000001 != 0, "IOCTL_BATTERY_QUERY_STATUS") // BATTERY_POWER_ON_LINE
+		if opts.Attributes {
+			setStructAttributes(b, "BATTERY_STATUS.", bs)
+		}
 	} else {
 		e.Current = err
 		e.ChargeRate = err
@@ -301,15 +466,39 @@ func systemGet(idx int) (*Battery, error) {
 	}
 
 	b.DesignVoltage, e.DesignVoltage = b.Voltage, e.Voltage
+	b.setSource(FieldDesignVoltage, e.DesignVoltage, Copied, b.source(FieldVoltage))
+
+	// The estimate is based on the averaged discharge rate,
+	// so the rate can be recovered from it.
+	if b.FirmwareTimeToEmpty > 0 && e.Current == nil {
+		b.AverageChargeRate = b.Current / b.FirmwareTimeToEmpty.Hours()
+		b.setSource(FieldAverageChargeRate, nil, Derived, b.source(FieldCurrent), b.source(FieldFirmwareTimeToEmpty))
+	}
+
+	postprocess(b, &e, opts, hints)
 
 	return b, e
 }
 
-func systemGetAll() ([]*Battery, error) {
+// DeviceIoControl calls might block on misbehaving drivers,
+// so they are abandoned if ctx is done first.
+func systemGet(ctx context.Context, idx int, opts Options) (*Battery, error) {
+	var b *Battery
+	var err error
+	if cerr := withContext(ctx, func() { b, err = getByIndex(idx, opts) }); cerr != nil {
+		return nil, cerr
+	}
+	return b, err
+}
+
+func systemGetAll(ctx context.Context, opts Options) ([]*Battery, error) {
 	var batteries []*Battery
 	var errors Errors
 	for i := 0; ; i++ {
-		b, err := systemGet(i)
+		b, err := systemGet(ctx, i, opts)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if err == ErrNotFound {
 			break
 		}
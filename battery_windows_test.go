@@ -0,0 +1,50 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReadTemperatureWindows(t *testing.T) {
+	cases := []struct {
+		in       uint32
+		out      float64
+		errorOut error
+	}{
+		{3081, 34.95, nil},
+		{2731, -0.05, nil},
+		{0, 0, ErrUnknownValue},
+	}
+
+	for i, c := range cases {
+		temperature, err := readTemperature(c.in)
+
+		if math.Abs(temperature-c.out) > 1e-9 {
+			t.Errorf("%d: %v != %v", i, temperature, c.out)
+		}
+		if err != c.errorOut {
+			t.Errorf("%d: %v != %v", i, err, c.errorOut)
+		}
+	}
+}
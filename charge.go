@@ -0,0 +1,106 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import "math"
+
+// chargeFields pairs charge fields with the energy fields
+// they can be converted from and to.
+var chargeFields = []struct{ charge, energy Field }{
+	{FieldChargeNow, FieldCurrent},
+	{FieldChargeFull, FieldFull},
+	{FieldChargeDesign, FieldDesign},
+	{FieldCurrentNow, FieldChargeRate},
+}
+
+// conversionVoltage returns the voltage used to convert given charge field,
+// according to opts, together with its source and error.
+func conversionVoltage(b *Battery, e *ErrPartial, charge Field, opts Options) (float64, string, error) {
+	switch {
+	case opts.Conversion == ConvertNominal && opts.NominalVoltage > 0:
+		return opts.NominalVoltage, "NominalVoltage", nil
+	case opts.Conversion != ConvertInstantaneous || charge == FieldChargeDesign:
+		return b.DesignVoltage, b.source(FieldDesignVoltage), e.DesignVoltage
+	}
+	return b.Voltage, b.source(FieldVoltage), e.Voltage
+}
+
+// nativeCharge handles a backend that read charge (in mAh or mA) into energy field f.
+// The reading is moved to its charge field and f is recalculated.
+func nativeCharge(b *Battery, e *ErrPartial, f, charge Field, opts Options) {
+	*b.float(charge) = *b.float(f)
+	e.Set(charge, e.Get(f))
+	if p, ok := b.Provenance[f]; ok {
+		b.Provenance[charge] = p
+	}
+	chargeToEnergy(b, e, f, charge, opts)
+}
+
+// chargeToEnergy sets energy field f from charge field.
+func chargeToEnergy(b *Battery, e *ErrPartial, f, charge Field, opts Options) {
+	voltage, source, err := conversionVoltage(b, e, charge, opts)
+	if err == nil {
+		err = e.Get(charge)
+	}
+	e.Set(f, err)
+	if err == nil {
+		*b.float(f) = *b.float(charge) * voltage
+	}
+	b.setSource(f, err, Derived, b.source(charge), source)
+}
+
+// averageCurrent sets AverageChargeRate from averaged current (in mA),
+// converted with the same voltage as CurrentNow.
+func averageCurrent(b *Battery, e *ErrPartial, current float64, err error, source string, opts Options) {
+	voltage, vsource, verr := conversionVoltage(b, e, FieldCurrentNow, opts)
+	if err == nil {
+		err = verr
+	}
+	e.Set(FieldAverageChargeRate, err)
+	if err == nil {
+		b.AverageChargeRate = current * voltage
+	}
+	b.setSource(FieldAverageChargeRate, err, Derived, source, vsource)
+}
+
+// deriveCharge fills charge fields not read natively by the backend
+// from the energy values.
+func deriveCharge(b *Battery, e *ErrPartial, opts Options) {
+	for _, c := range chargeFields {
+		if _, ok := b.Provenance[c.charge]; ok || e.Get(c.charge) != nil {
+			continue
+		}
+		voltage, source, err := conversionVoltage(b, e, c.charge, opts)
+		if err == nil {
+			err = e.Get(c.energy)
+		}
+		if err == nil && voltage == 0 {
+			err = ErrUnknownValue
+		}
+		e.Set(c.charge, err)
+		if err == nil {
+			*b.float(c.charge) = *b.float(c.energy) / voltage
+		}
+		b.setSource(c.charge, err, Derived, b.source(c.energy), source)
+	}
+	b.CurrentNow = math.Abs(b.CurrentNow)
+}
@@ -0,0 +1,118 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"strings"
+	"time"
+)
+
+// Chemistry type enumerates battery chemistries, using platform agnostic naming.
+type Chemistry int8
+
+const (
+	ChemistryUnknown Chemistry = iota
+	ChemistryLiIon
+	ChemistryLiPoly
+	ChemistryLiFe
+	ChemistryLiMn
+	ChemistryNiMH
+	ChemistryNiCd
+	ChemistryNiZn
+	ChemistryLeadAcid
+	// ChemistryAlkaline specifies rechargeable alkaline-manganese.
+	ChemistryAlkaline
+)
+
+var chemistries = map[Chemistry]string{
+	ChemistryUnknown:  "Unknown",
+	ChemistryLiIon:    "LiIon",
+	ChemistryLiPoly:   "LiPoly",
+	ChemistryLiFe:     "LiFe",
+	ChemistryLiMn:     "LiMn",
+	ChemistryNiMH:     "NiMH",
+	ChemistryNiCd:     "NiCd",
+	ChemistryNiZn:     "NiZn",
+	ChemistryLeadAcid: "LeadAcid",
+	ChemistryAlkaline: "Alkaline",
+}
+
+func (c Chemistry) String() string {
+	return chemistries[c]
+}
+
+// chemistryAliases map the names used by the systems, lowercased
+// and stripped of punctuation, to chemistries.
+var chemistryAliases = map[string]Chemistry{
+	"liion":    ChemistryLiIon, // Linux
+	"lion":     ChemistryLiIon, // Windows, ACPI
+	"lii":      ChemistryLiIon, // Windows
+	"li":       ChemistryLiIon,
+	"lipoly":   ChemistryLiPoly, // Linux
+	"lip":      ChemistryLiPoly,
+	"lipo":     ChemistryLiPoly,
+	"life":     ChemistryLiFe, // Linux
+	"lifepo4":  ChemistryLiFe,
+	"limn":     ChemistryLiMn, // Linux
+	"nimh":     ChemistryNiMH,
+	"nicd":     ChemistryNiCd,
+	"nizn":     ChemistryNiZn,
+	"pbac":     ChemistryLeadAcid, // Windows
+	"pb":       ChemistryLeadAcid,
+	"leadacid": ChemistryLeadAcid,
+	"ram":      ChemistryAlkaline, // Windows
+}
+
+// parseChemistry maps chemistry name reported by the system, e.g. "Li-ion" or "LION".
+func parseChemistry(name string) Chemistry {
+	key := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(name))
+	return chemistryAliases[key]
+}
+
+// date returns the date or zero time if year is not known.
+// Unknown month or day default to the first one.
+func date(year, month, day int) time.Time {
+	if year <= 0 {
+		return time.Time{}
+	}
+	if month < 1 || month > 12 {
+		month = 1
+	}
+	if day < 1 || day > 31 {
+		day = 1
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// sbsDate decodes date packed as in Smart Battery Data Specification,
+// (year - 1980) * 512 + month * 32 + day.
+func sbsDate(packed int) time.Time {
+	if packed <= 0 {
+		return time.Time{}
+	}
+	return date(1980+packed>>9, packed>>5&0xf, packed&0x1f)
+}
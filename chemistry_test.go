@@ -0,0 +1,73 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseChemistry(t *testing.T) {
+	cases := []struct {
+		in  string
+		out Chemistry
+	}{
+		{"Li-ion", ChemistryLiIon},
+		{"LION", ChemistryLiIon},
+		{"Li-poly", ChemistryLiPoly},
+		{"LiFe", ChemistryLiFe},
+		{"PbAc", ChemistryLeadAcid},
+		{"NiMH", ChemistryNiMH},
+		{"RAM", ChemistryAlkaline},
+		{"Unknown", ChemistryUnknown},
+		{"", ChemistryUnknown},
+	}
+
+	for i, c := range cases {
+		chemistry := parseChemistry(c.in)
+
+		if chemistry != c.out {
+			t.Errorf("%d: %v != %v", i, chemistry, c.out)
+		}
+	}
+}
+
+func TestSBSDate(t *testing.T) {
+	cases := []struct {
+		in  int
+		out time.Time
+	}{
+		{41*512 + 3*32 + 15, time.Date(2021, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{41 * 512, time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{0, time.Time{}},
+		{-1, time.Time{}},
+	}
+
+	for i, c := range cases {
+		date := sbsDate(c.in)
+
+		if !reflect.DeepEqual(date, c.out) {
+			t.Errorf("%d: %v != %v", i, date, c.out)
+		}
+	}
+}
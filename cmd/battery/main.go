@@ -22,6 +22,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -30,36 +31,49 @@ import (
 )
 
 func printBattery(idx int, bat *battery.Battery) {
-	fmt.Printf(
-		"BAT%d: %s, %.2f%%",
-		idx,
-		bat.State,
-		bat.Current/bat.Full*100,
-	)
+	percent, _ := bat.Percent()
+	fmt.Printf("BAT%d: %s, %.2f%%", idx, bat.State, percent)
 	defer fmt.Printf(" [Voltage: %.2fV (design: %.2fV)]\n", bat.Voltage, bat.DesignVoltage)
 
 	var str string
-	var timeNum float64
+	var duration time.Duration
+	var err error
 	switch bat.State.Raw {
 	case battery.Discharging:
-		if bat.ChargeRate == 0 {
+		str = "remaining"
+		duration, err = bat.TimeToEmpty()
+		if errors.Is(err, battery.ErrZeroValue) {
 			fmt.Print(", discharging at zero rate - will never fully discharge")
 			return
 		}
-		str = "remaining"
-		timeNum = bat.Current / bat.ChargeRate
 	case battery.Charging:
-		if bat.ChargeRate == 0 {
+		str = "until charged"
+		duration, err = bat.TimeToFull()
+		if errors.Is(err, battery.ErrZeroValue) {
 			fmt.Print(", charging at zero rate - will never fully charge")
 			return
 		}
-		str = "until charged"
-		timeNum = (bat.Full - bat.Current) / bat.ChargeRate
 	default:
 		return
 	}
-	duration, _ := time.ParseDuration(fmt.Sprintf("%fh", timeNum))
-	fmt.Printf(", %s %s", duration, str)
+	if err == nil {
+		fmt.Printf(", %s %s", duration.Round(time.Second), str)
+	}
+}
+
+// printable tells whether the values printBattery shows were retrieved despite err,
+// as errors of other fields (e.g. a missing cycle count) do not matter here.
+func printable(err error) bool {
+	perr, ok := err.(battery.ErrPartial)
+	if !ok {
+		return false
+	}
+	for _, f := range []battery.Field{battery.FieldState, battery.FieldCurrent, battery.FieldFull, battery.FieldVoltage} {
+		if perr.Get(f) != nil {
+			return false
+		}
+	}
+	return true
 }
 
 func main() {
@@ -74,7 +88,7 @@ func main() {
 	}
 	errs, partialErrs := err.(battery.Errors)
 	for i, bat := range batteries {
-		if partialErrs && errs[i] != nil {
+		if partialErrs && errs[i] != nil && !printable(errs[i]) {
 			fmt.Fprintf(os.Stderr, "Error getting info for BAT%d: %s\n", i, errs[i])
 			continue
 		}
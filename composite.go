@@ -0,0 +1,185 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// combinedFields are the fields summed up by Combine.
+var combinedFields = []Field{FieldCurrent, FieldFull, FieldDesign}
+
+// Combine aggregates batteries into one, as if they were a single big pack.
+//
+// Energies and rates are summed up, so that Capacity is weighted by the size
+// of each battery rather than being an average of percentages. State is
+// merged giving precedence to the direction the energy flows in.
+// Voltages, charge values, averaged rates and firmware estimates are not combined.
+//
+// UPS and peripheral batteries, as told by Kind, are left out.
+// Fields missing from any of the included batteries are reported in ErrPartial.
+// If there is no battery to combine, ErrNotFound is returned.
+func Combine(batteries []*Battery) (*Battery, error) {
+	var included []*Battery
+	for _, b := range batteries {
+		if b != nil && b.Kind != KindUPS && b.Kind != KindPeripheral {
+			included = append(included, b)
+		}
+	}
+	if len(included) == 0 {
+		return nil, ErrNotFound
+	}
+
+	ids := make([]string, len(included))
+	for i, b := range included {
+		ids[i] = b.ID
+	}
+	c := &Battery{ID: strings.Join(ids, "+"), Name: "Composite", Kind: KindSystem}
+	e := ErrPartial{}
+	source := strings.Join(ids, ", ")
+
+	// missing returns an error if any of the batteries does not have f.
+	missing := func(f Field) error {
+		for _, b := range included {
+			if !b.has(f) {
+				return fmt.Errorf("%s: %s: %w", b.ID, f, ErrValueNotFound)
+			}
+		}
+		return nil
+	}
+
+	for _, f := range combinedFields {
+		err := missing(f)
+		e.Set(f, err)
+		if err == nil {
+			for _, b := range included {
+				*c.float(f) += *b.float(f)
+			}
+		}
+		c.setSource(f, err, Derived, source)
+	}
+
+	// Batteries that are not charging nor discharging do not need a rate.
+	var err error
+	for _, b := range included {
+		flow := b.flow()
+		if flow != 0 && !b.has(FieldChargeRate) {
+			err = fmt.Errorf("%s: %s: %w", b.ID, FieldChargeRate, ErrValueNotFound)
+			c.NetPower = 0
+			break
+		}
+		c.NetPower += float64(flow) * b.ChargeRate
+	}
+	e.ChargeRate = err
+	e.Set(FieldNetPower, err)
+	if err == nil {
+		if c.NetPower < 0 {
+			c.ChargeRate = -c.NetPower
+		} else {
+			c.ChargeRate = c.NetPower
+		}
+	}
+	c.setSource(FieldChargeRate, err, Derived, source)
+	c.setSource(FieldNetPower, err, Derived, source)
+
+	e.Capacity = c.need(FieldCurrent, FieldFull)
+	if e.Capacity == nil {
+		c.Capacity = c.Current / c.Full * 100
+	}
+	c.setSource(FieldCapacity, e.Capacity, Derived, source)
+
+	e.State = missing(FieldState)
+	if e.State == nil {
+		c.State = combineStates(included, c.NetPower)
+	}
+	c.setSource(FieldState, e.State, Derived, source)
+
+	if e.isNil() {
+		return c, nil
+	}
+	return c, e
+}
+
+// combineStates merges states of the batteries.
+//
+// Discharging and Charging win over the other states, if both are present
+// the sign of netPower decides. Full and Empty need all the batteries to agree,
+// otherwise the states without any energy flow merge into Idle.
+func combineStates(batteries []*Battery, netPower float64) State {
+	counts := make(map[AgnosticState]int)
+	specifics := make([]string, len(batteries))
+	for i, b := range batteries {
+		counts[b.State.Raw]++
+		specifics[i] = b.State.Raw.String()
+	}
+	state := State{specific: strings.Join(specifics, ", ")}
+
+	switch {
+	case counts[Charging] > 0 && counts[Discharging] > 0:
+		state.Raw = Discharging
+		if netPower > 0 {
+			state.Raw = Charging
+		}
+	case counts[Discharging] > 0:
+		state.Raw = Discharging
+	case counts[Charging] > 0:
+		state.Raw = Charging
+	case counts[Full] == len(batteries):
+		state.Raw = Full
+	case counts[Empty] == len(batteries):
+		state.Raw = Empty
+	case counts[Unknown] > 0 || counts[Undefined] > 0:
+		state.Raw = Unknown
+	default:
+		state.Raw = Idle
+	}
+	return state
+}
+
+// GetComposite combines all the batteries into one, see Combine.
+//
+// Batteries that could not be retrieved at all are left out.
+func GetComposite() (*Battery, error) {
+	return GetCompositeWithOptions(context.Background(), Options{})
+}
+
+// GetCompositeWithOptions is like GetComposite, but uses given context and options.
+func GetCompositeWithOptions(ctx context.Context, opts Options) (*Battery, error) {
+	bs, err := GetAllWithOptions(ctx, opts)
+	errs, isErrors := err.(Errors)
+	if err != nil && !isErrors {
+		return nil, err
+	}
+	var retrieved []*Battery
+	for i, b := range bs {
+		if i < len(errs) {
+			if _, fatal := errs[i].(ErrFatal); fatal {
+				continue
+			}
+		}
+		retrieved = append(retrieved, b)
+	}
+	c, err := Combine(retrieved)
+	return c, contextError(ctx, wrapError(err))
+}
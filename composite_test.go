@@ -0,0 +1,134 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCombine(t *testing.T) {
+	derived := func(source string, fields ...Field) map[Field]Provenance {
+		p := make(map[Field]Provenance)
+		for _, f := range fields {
+			p[f] = Provenance{Derived, source}
+		}
+		return p
+	}
+	all := []Field{FieldState, FieldCapacity, FieldCurrent, FieldFull, FieldDesign, FieldChargeRate, FieldNetPower}
+	mouse := &Battery{ID: "hidpp_battery_0", Kind: KindPeripheral, State: State{Raw: Discharging}, Current: 1000, Full: 1000, ChargeRate: 100}
+
+	cases := []struct {
+		batteriesIn []*Battery
+		batteryOut  *Battery
+		errorOut    error
+	}{{
+		[]*Battery{
+			{ID: "BAT0", State: State{Raw: Discharging}, Current: 20000, Full: 40000, Design: 45000, ChargeRate: 5000},
+			{ID: "BAT1", State: State{Raw: Idle}, Current: 30000, Full: 60000, Design: 60000},
+			mouse,
+		},
+		&Battery{
+			ID: "BAT0+BAT1", Name: "Composite", Kind: KindSystem,
+			State:    State{Raw: Discharging, specific: "Discharging, Idle"},
+			Capacity: 50, Current: 50000, Full: 100000, Design: 105000,
+			ChargeRate: 5000, NetPower: -5000,
+			Provenance: derived("BAT0, BAT1", all...),
+		},
+		nil,
+	}, {
+		[]*Battery{
+			{ID: "BAT0", State: State{Raw: Charging}, Current: 20000, Full: 40000, ChargeRate: 8000},
+			{ID: "BAT1", State: State{Raw: Discharging}, Current: 30000, Full: 60000, ChargeRate: 6000},
+		},
+		&Battery{
+			ID: "BAT0+BAT1", Name: "Composite", Kind: KindSystem,
+			State:    State{Raw: Charging, specific: "Charging, Discharging"},
+			Capacity: 50, Current: 50000, Full: 100000,
+			ChargeRate: 2000, NetPower: 2000,
+			Provenance: derived("BAT0, BAT1", all...),
+		},
+		nil,
+	}, {
+		[]*Battery{
+			{ID: "BAT0", State: State{Raw: Full}, Current: 40000, Full: 40000},
+			{
+				ID: "BAT1", State: State{Raw: Full}, Current: 60000,
+				Provenance: map[Field]Provenance{FieldState: {Measured, "status"}, FieldCurrent: {Measured, "energy_now"}},
+			},
+		},
+		&Battery{
+			ID: "BAT0+BAT1", Name: "Composite", Kind: KindSystem,
+			State:      State{Raw: Full, specific: "Full, Full"},
+			Current:    100000,
+			Provenance: derived("BAT0, BAT1", FieldState, FieldCurrent, FieldChargeRate, FieldNetPower),
+		},
+		ErrPartial{
+			Full:     fmt.Errorf("BAT1: Full: %w", ErrValueNotFound),
+			Design:   fmt.Errorf("BAT1: Design: %w", ErrValueNotFound),
+			Capacity: fmt.Errorf("Full: %w", ErrValueNotFound),
+		},
+	}, {
+		[]*Battery{mouse, nil},
+		nil,
+		ErrNotFound,
+	}}
+
+	for i, c := range cases {
+		battery, err := Combine(c.batteriesIn)
+
+		if !reflect.DeepEqual(err, c.errorOut) {
+			t.Errorf("%d: %v != %v", i, err, c.errorOut)
+		}
+		if !reflect.DeepEqual(battery, c.batteryOut) {
+			t.Errorf("%d: %v != %v", i, battery, c.batteryOut)
+		}
+	}
+}
+
+func TestCombineStates(t *testing.T) {
+	cases := []struct {
+		statesIn []AgnosticState
+		stateOut AgnosticState
+	}{
+		{[]AgnosticState{Charging, Idle}, Charging},
+		{[]AgnosticState{Full, Full}, Full},
+		{[]AgnosticState{Empty, Empty}, Empty},
+		{[]AgnosticState{Full, Empty}, Idle},
+		{[]AgnosticState{Full, Unknown}, Unknown},
+		{[]AgnosticState{Idle, Undefined}, Unknown},
+	}
+
+	for i, c := range cases {
+		batteries := make([]*Battery, len(c.statesIn))
+		for j, s := range c.statesIn {
+			batteries[j] = &Battery{State: State{Raw: s}}
+		}
+
+		state := combineStates(batteries, 0)
+
+		if state.Raw != c.stateOut {
+			t.Errorf("%d: %v != %v", i, state.Raw, c.stateOut)
+		}
+	}
+}
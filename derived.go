@@ -0,0 +1,153 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"fmt"
+	"time"
+)
+
+// has reports whether value of f was retrieved.
+// Batteries without provenance (e.g. from custom providers) are assumed to have all the values.
+func (b *Battery) has(f Field) bool {
+	if b.Provenance == nil {
+		return true
+	}
+	_, ok := b.Provenance[f]
+	return ok
+}
+
+// need returns an error if any of the fields was not retrieved or is zero.
+func (b *Battery) need(fields ...Field) error {
+	for _, f := range fields {
+		if !b.has(f) {
+			return fmt.Errorf("%s: %w", f, ErrValueNotFound)
+		}
+		if *b.float(f) == 0 {
+			return fmt.Errorf("%s: %w", f, ErrZeroValue)
+		}
+	}
+	return nil
+}
+
+// flow returns 1 if the battery is charging, -1 if it is discharging and 0 otherwise.
+// It goes by State, falling back to the sign of NetPower.
+func (b *Battery) flow() int {
+	if b.has(FieldState) {
+		switch b.State.Raw {
+		case Charging:
+			return 1
+		case Discharging, Empty:
+			return -1
+		}
+	}
+	switch {
+	case !b.has(FieldNetPower) || b.NetPower == 0:
+		return 0
+	case b.NetPower > 0:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// Percent returns current charge level.
+//
+// It is calculated from Current and Full, falling back
+// to the Capacity reported by the system if they are not available.
+func (b *Battery) Percent() (Percent, error) {
+	if b.has(FieldCurrent) && b.need(FieldFull) == nil {
+		return Percent(b.Current / b.Full * 100), nil
+	}
+	if b.has(FieldCapacity) {
+		return Percent(b.Capacity), nil
+	}
+	return 0, fmt.Errorf("%s: %w", FieldCapacity, ErrValueNotFound)
+}
+
+// TimeToEmpty returns how long it takes to discharge the battery at the current rate.
+//
+// If the battery is not discharging, ErrNotApplicable is returned.
+func (b *Battery) TimeToEmpty() (time.Duration, error) {
+	return b.timeTo(0, -1)
+}
+
+// TimeToFull returns how long it takes to charge the battery at the current rate.
+//
+// If the battery is not charging, ErrNotApplicable is returned.
+func (b *Battery) TimeToFull() (time.Duration, error) {
+	if err := b.need(FieldFull); err != nil {
+		return 0, err
+	}
+	return b.timeTo(b.Full, 1)
+}
+
+// TimeToPercent returns how long it takes to reach given charge level at the current rate.
+//
+// If the battery is not charging or discharging towards p, ErrNotApplicable is returned.
+func (b *Battery) TimeToPercent(p Percent) (time.Duration, error) {
+	if err := b.need(FieldFull); err != nil {
+		return 0, err
+	}
+	if !b.has(FieldCurrent) {
+		return 0, fmt.Errorf("%s: %w", FieldCurrent, ErrValueNotFound)
+	}
+	target := b.Full * p.Fraction()
+	switch {
+	case target > b.Current:
+		return b.timeTo(target, 1)
+	case target < b.Current:
+		return b.timeTo(target, -1)
+	}
+	return 0, nil
+}
+
+// timeTo returns time to reach target energy, flowing in given direction.
+func (b *Battery) timeTo(target float64, direction int) (time.Duration, error) {
+	if !b.has(FieldCurrent) {
+		return 0, fmt.Errorf("%s: %w", FieldCurrent, ErrValueNotFound)
+	}
+	if b.flow() != direction {
+		return 0, ErrNotApplicable
+	}
+	if err := b.need(FieldChargeRate); err != nil {
+		return 0, err
+	}
+	energy := Energy(target - b.Current)
+	if direction < 0 {
+		energy = -energy
+	}
+	if energy < 0 {
+		// Already past the target, e.g. reported above the last full capacity.
+		energy = 0
+	}
+	return energy.Div(Power(b.ChargeRate)), nil
+}
+
+// Wear returns how much of the design capacity was lost, e.g. 15 for a battery
+// that can only be charged up to 85% of its design capacity.
+func (b *Battery) Wear() (Percent, error) {
+	if err := b.need(FieldFull, FieldDesign); err != nil {
+		return 0, err
+	}
+	return Percent((b.Design - b.Full) / b.Design * 100), nil
+}
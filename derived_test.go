@@ -0,0 +1,137 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// measured returns provenance with given fields present.
+func measured(fields ...Field) map[Field]Provenance {
+	p := make(map[Field]Provenance)
+	for _, f := range fields {
+		p[f] = Provenance{}
+	}
+	return p
+}
+
+func TestPercent(t *testing.T) {
+	cases := []struct {
+		batteryIn  *Battery
+		percentOut Percent
+		errorOut   error
+	}{
+		{&Battery{Current: 30, Full: 40, Capacity: 70}, 75, nil},
+		{&Battery{Current: 30, Full: 40, Capacity: 70, Provenance: measured(FieldCurrent, FieldCapacity)}, 70, nil},
+		{&Battery{Current: 30, Capacity: 70}, 70, nil},
+		{&Battery{Current: 30, Full: 40, Provenance: measured(FieldFull)}, 0, ErrValueNotFound},
+	}
+
+	for i, c := range cases {
+		percent, err := c.batteryIn.Percent()
+		if percent != c.percentOut {
+			t.Errorf("%d: %v != %v", i, percent, c.percentOut)
+		}
+		if !errors.Is(err, c.errorOut) {
+			t.Errorf("%d: %v != %v", i, err, c.errorOut)
+		}
+	}
+}
+
+func TestTimeTo(t *testing.T) {
+	discharging := State{Raw: Discharging}
+	charging := State{Raw: Charging}
+	idle := State{Raw: Idle}
+
+	cases := []struct {
+		batteryIn *Battery
+		timeTo    func(b *Battery) (time.Duration, error)
+		timeOut   time.Duration
+		errorOut  error
+	}{
+		{&Battery{State: discharging, Current: 30000, Full: 40000, ChargeRate: 10000}, (*Battery).TimeToEmpty, 3 * time.Hour, nil},
+		{&Battery{State: charging, Current: 30000, Full: 40000, ChargeRate: 20000}, (*Battery).TimeToFull, 30 * time.Minute, nil},
+		{&Battery{State: charging, Current: 30000, Full: 40000, ChargeRate: 20000}, (*Battery).TimeToEmpty, 0, ErrNotApplicable},
+		{&Battery{State: discharging, Current: 30000, Full: 40000, ChargeRate: 0}, (*Battery).TimeToEmpty, 0, ErrZeroValue},
+		{&Battery{State: charging, Current: 41000, Full: 40000, ChargeRate: 1000}, (*Battery).TimeToFull, 0, nil},
+		{&Battery{State: idle, Current: 30000, Full: 40000, ChargeRate: 1000, NetPower: -1000}, (*Battery).TimeToEmpty, 30 * time.Hour, nil},
+		{&Battery{State: idle, Current: 30000, Full: 40000}, (*Battery).TimeToFull, 0, ErrNotApplicable},
+		{
+			&Battery{State: discharging, Current: 30000, Full: 40000, ChargeRate: 10000, Provenance: measured(FieldState, FieldCurrent, FieldFull)},
+			(*Battery).TimeToEmpty, 0, ErrValueNotFound,
+		},
+		{
+			&Battery{State: charging, Current: 30000, Full: 40000, ChargeRate: 10000, Provenance: measured(FieldState, FieldCurrent, FieldChargeRate)},
+			(*Battery).TimeToFull, 0, ErrValueNotFound,
+		},
+		{
+			&Battery{State: charging, Current: 20000, Full: 40000, ChargeRate: 10000},
+			func(b *Battery) (time.Duration, error) { return b.TimeToPercent(80) }, 72 * time.Minute, nil,
+		},
+		{
+			&Battery{State: discharging, Current: 20000, Full: 40000, ChargeRate: 10000},
+			func(b *Battery) (time.Duration, error) { return b.TimeToPercent(20) }, 72 * time.Minute, nil,
+		},
+		{
+			&Battery{State: discharging, Current: 20000, Full: 40000, ChargeRate: 10000},
+			func(b *Battery) (time.Duration, error) { return b.TimeToPercent(80) }, 0, ErrNotApplicable,
+		},
+		{
+			&Battery{State: discharging, Current: 20000, Full: 40000, ChargeRate: 10000},
+			func(b *Battery) (time.Duration, error) { return b.TimeToPercent(50) }, 0, nil,
+		},
+	}
+
+	for i, c := range cases {
+		d, err := c.timeTo(c.batteryIn)
+		if d != c.timeOut {
+			t.Errorf("%d: %v != %v", i, d, c.timeOut)
+		}
+		if !errors.Is(err, c.errorOut) {
+			t.Errorf("%d: %v != %v", i, err, c.errorOut)
+		}
+	}
+}
+
+func TestWear(t *testing.T) {
+	cases := []struct {
+		batteryIn *Battery
+		wearOut   Percent
+		errorOut  error
+	}{
+		{&Battery{Full: 45000, Design: 50000}, 10, nil},
+		{&Battery{Full: 45000}, 0, ErrZeroValue},
+		{&Battery{Full: 45000, Design: 50000, Provenance: measured(FieldFull)}, 0, ErrValueNotFound},
+	}
+
+	for i, c := range cases {
+		wear, err := c.batteryIn.Wear()
+		if wear != c.wearOut {
+			t.Errorf("%d: %v != %v", i, wear, c.wearOut)
+		}
+		if !errors.Is(err, c.errorOut) {
+			t.Errorf("%d: %v != %v", i, err, c.errorOut)
+		}
+	}
+}
@@ -21,13 +21,39 @@
 
 package battery
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // ErrNotFound variable represents battery not found error.
 //
-// Only ever returned wrapped in ErrFatal.
+// Only ever returned wrapped in ErrFatal, use errors.Is to check for it.
 var ErrNotFound = fmt.Errorf("Not found")
 
+// ErrUnknownValue variable says that the system reported a value,
+// but explicitly marked it as unknown (e.g. BATTERY_UNKNOWN_CAPACITY on Windows).
+//
+// Only ever returned as a field of ErrPartial.
+var ErrUnknownValue = fmt.Errorf("Unknown value received")
+
+// ErrValueNotFound variable says that the system did not report a value at all.
+//
+// Only ever returned as a field of ErrPartial.
+var ErrValueNotFound = fmt.Errorf("Value not found")
+
+// ErrUnsupported variable says that retrieving a value (or battery information
+// as a whole) is not supported on the current platform.
+var ErrUnsupported = fmt.Errorf("Not supported")
+
+// ErrZeroValue variable says that a value a calculation depends on is zero,
+// e.g. the charge rate when calculating time to empty.
+var ErrZeroValue = fmt.Errorf("Zero value")
+
+// ErrNotApplicable variable says that a calculation does not make sense
+// in the current battery state, e.g. time to empty of a charging battery.
+var ErrNotApplicable = fmt.Errorf("Not applicable")
+
 // ErrAllNotNil variable says that backend returned ErrPartial with
 // all fields having not nil values, hence it was converted to ErrFatal.
 //
@@ -50,14 +76,98 @@ func (f ErrFatal) Error() string {
 	return fmt.Sprintf("Could not retrieve battery info: `%s`", f.Err)
 }
 
+func (f ErrFatal) Unwrap() error {
+	return f.Err
+}
+
+// Field type enumerates Battery fields that are retrieved separately
+// and hence can fail independently of each other.
+type Field int8
+
+const (
+	FieldState Field = iota
+	FieldCapacity
+	FieldCurrent
+	FieldFull
+	FieldDesign
+	FieldChargeRate
+	FieldVoltage
+	FieldDesignVoltage
+	FieldNetPower
+	FieldChargeNow
+	FieldChargeFull
+	FieldChargeDesign
+	FieldCurrentNow
+	FieldFirmwareTimeToEmpty
+	FieldFirmwareTimeToFull
+	FieldAverageChargeRate
+	FieldCycleCount
+	FieldTemperature
+	fieldCount
+)
+
+var fieldNames = [fieldCount]string{
+	FieldState:         "State",
+	FieldCapacity:      "Capacity",
+	FieldCurrent:       "Current",
+	FieldFull:          "Full",
+	FieldDesign:        "Design",
+	FieldChargeRate:    "ChargeRate",
+	FieldVoltage:       "Voltage",
+	FieldDesignVoltage: "DesignVoltage",
+	FieldNetPower:      "NetPower",
+	FieldChargeNow:     "ChargeNow",
+	FieldChargeFull:    "ChargeFull",
+	FieldChargeDesign:  "ChargeDesign",
+	FieldCurrentNow:    "CurrentNow",
+
+	FieldFirmwareTimeToEmpty: "FirmwareTimeToEmpty",
+	FieldFirmwareTimeToFull:  "FirmwareTimeToFull",
+	FieldAverageChargeRate:   "AverageChargeRate",
+	FieldCycleCount:          "CycleCount",
+	FieldTemperature:         "Temperature",
+}
+
+func (f Field) String() string {
+	if f < 0 || f >= fieldCount {
+		return fmt.Sprintf("Field(%d)", f)
+	}
+	return fieldNames[f]
+}
+
+// Fields returns all the fields, in order.
+func Fields() []Field {
+	fields := make([]Field, fieldCount)
+	for i := range fields {
+		fields[i] = Field(i)
+	}
+	return fields
+}
+
+// primaryFields are the ones that decide whether ErrPartial should be
+// converted to ErrFatal. Fields added later on are not part of this set,
+// so that adding new attributes does not change what "all failed" means.
+var primaryFields = []Field{
+	FieldState,
+	FieldCapacity,
+	FieldCurrent,
+	FieldFull,
+	FieldDesign,
+	FieldChargeRate,
+	FieldVoltage,
+	FieldDesignVoltage,
+}
+
 // ErrPartial type represents a partial error.
 //
 // It indicates that there were problems retrieving some of the data,
 // but some was also retrieved successfully.
 // If there would be all nils, nil is returned instead.
-// If there would be all not nils, ErrFatal is returned instead.
+// If there would be all not nils in the primary fields (all of the named ones),
+// ErrFatal is returned instead.
 //
-// The fields represent fields in the Battery type.
+// The named fields represent fields in the Battery type. They are kept for
+// convenience, errors of all the fields are accessible with Get and Failed.
 type ErrPartial struct {
 	State         error
 	Capacity      error
@@ -67,53 +177,101 @@ type ErrPartial struct {
 	ChargeRate    error
 	Voltage       error
 	DesignVoltage error
+
+	// Errors for fields without a named member, indexed by Field.
+	others [fieldCount]error
+}
+
+func (p *ErrPartial) ref(f Field) *error {
+	switch f {
+	case FieldState:
+		return &p.State
+	case FieldCapacity:
+		return &p.Capacity
+	case FieldCurrent:
+		return &p.Current
+	case FieldFull:
+		return &p.Full
+	case FieldDesign:
+		return &p.Design
+	case FieldChargeRate:
+		return &p.ChargeRate
+	case FieldVoltage:
+		return &p.Voltage
+	case FieldDesignVoltage:
+		return &p.DesignVoltage
+	}
+	return &p.others[f]
+}
+
+// Get returns error of given field.
+func (p ErrPartial) Get(f Field) error {
+	if f < 0 || f >= fieldCount {
+		return nil
+	}
+	return *p.ref(f)
+}
+
+// Set sets error of given field.
+func (p *ErrPartial) Set(f Field, err error) {
+	if f < 0 || f >= fieldCount {
+		return
+	}
+	*p.ref(f) = err
+}
+
+// Failed returns fields that have not nil errors, in order.
+func (p ErrPartial) Failed() []Field {
+	var fields []Field
+	for f := Field(0); f < fieldCount; f++ {
+		if p.Get(f) != nil {
+			fields = append(fields, f)
+		}
+	}
+	return fields
 }
 
 func (p ErrPartial) Error() string {
 	if p.isNil() {
 		return "{}"
 	}
-	errors := map[string]error{
-		"State":         p.State,
-		"Capacity":      p.Capacity,
-		"Current":       p.Current,
-		"Full":          p.Full,
-		"Design":        p.Design,
-		"ChargeRate":    p.ChargeRate,
-		"Voltage":       p.Voltage,
-		"DesignVoltage": p.DesignVoltage,
-	}
-	keys := []string{"State", "Capacity", "Current", "Full", "Design", "ChargeRate", "Voltage", "DesignVoltage"}
 	s := "{"
-	for _, name := range keys {
-		err := errors[name]
-		if err != nil {
-			s += fmt.Sprintf("%s:%s ", name, err.Error())
-		}
+	for _, f := range p.Failed() {
+		s += fmt.Sprintf("%s:%s ", f, p.Get(f).Error())
 	}
 	return s[:len(s)-1] + "}"
 }
 
+// Unwrap returns all not nil field errors.
+func (p ErrPartial) Unwrap() []error {
+	var errs []error
+	for _, f := range p.Failed() {
+		errs = append(errs, p.Get(f))
+	}
+	return errs
+}
+
+// Is reports whether any of the field errors matches target.
+func (p ErrPartial) Is(target error) bool {
+	return isAny(p.Unwrap(), target)
+}
+
+// As finds the first field error that matches target.
+func (p ErrPartial) As(target interface{}) bool {
+	return asAny(p.Unwrap(), target)
+}
+
 func (p ErrPartial) isNil() bool {
-	return p.State == nil &&
-		p.Capacity == nil &&
-		p.Current == nil &&
-		p.Full == nil &&
-		p.Design == nil &&
-		p.ChargeRate == nil &&
-		p.Voltage == nil &&
-		p.DesignVoltage == nil
+	return len(p.Failed()) == 0
 }
 
 func (p ErrPartial) noNil() bool {
-	return p.State != nil &&
-		p.Capacity != nil &&
-		p.Current != nil &&
-		p.Full != nil &&
-		p.Design != nil &&
-		p.ChargeRate != nil &&
-		p.Voltage != nil &&
-		p.DesignVoltage != nil
+	for _, f := range primaryFields {
+		if p.Get(f) == nil {
+			return false
+		}
+	}
+	return true
 }
 
 // Errors type represents an array of ErrFatal, ErrPartial or nil values.
@@ -134,6 +292,46 @@ func (e Errors) Error() string {
 	return s + "]"
 }
 
+// Unwrap returns all not nil errors.
+func (e Errors) Unwrap() []error {
+	var errs []error
+	for _, err := range e {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Is reports whether any of the errors matches target.
+func (e Errors) Is(target error) bool {
+	return isAny(e.Unwrap(), target)
+}
+
+// As finds the first error that matches target.
+func (e Errors) As(target interface{}) bool {
+	return asAny(e.Unwrap(), target)
+}
+
+// Go versions before 1.20 do not traverse Unwrap() []error on their own.
+func isAny(errs []error, target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func asAny(errs []error, target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 func wrapError(err error) error {
 	if perr, ok := err.(ErrPartial); ok {
 		if perr.isNil() {
@@ -23,6 +23,7 @@ package battery
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -36,7 +37,8 @@ func TestErrPartial(t *testing.T) {
 		{ErrPartial{}, "{}", true, false},
 		{ErrPartial{Full: errors.New("t1")}, "{Full:t1}", false, false},
 		{ErrPartial{State: errors.New("t2"), Full: errors.New("t3")}, "{State:t2 Full:t3}", false, false},
-		{ErrPartial{State: errors.New("t4"), Current: errors.New("t5"), Full: errors.New("t6"), Design: errors.New("t7"), ChargeRate: errors.New("t8"), Voltage: errors.New("t9"), DesignVoltage: errors.New("t10")}, "{State:t4 Current:t5 Full:t6 Design:t7 ChargeRate:t8 Voltage:t9 DesignVoltage:t10}", false, true},
+		{ErrPartial{State: errors.New("t4"), Current: errors.New("t5"), Full: errors.New("t6"), Design: errors.New("t7"), ChargeRate: errors.New("t8"), Voltage: errors.New("t9"), DesignVoltage: errors.New("t10")}, "{State:t4 Current:t5 Full:t6 Design:t7 ChargeRate:t8 Voltage:t9 DesignVoltage:t10}", false, false},
+		{ErrPartial{State: errors.New("t4"), Capacity: errors.New("t11"), Current: errors.New("t5"), Full: errors.New("t6"), Design: errors.New("t7"), ChargeRate: errors.New("t8"), Voltage: errors.New("t9"), DesignVoltage: errors.New("t10")}, "{State:t4 Capacity:t11 Current:t5 Full:t6 Design:t7 ChargeRate:t8 Voltage:t9 DesignVoltage:t10}", false, true},
 	}
 
 	for i, c := range cases {
@@ -76,3 +78,88 @@ func TestErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorsIs(t *testing.T) {
+	cases := []struct {
+		in     error
+		target error
+		is     bool
+	}{
+		{ErrFatal{ErrNotFound}, ErrNotFound, true},
+		{ErrFatal{ErrAllNotNil}, ErrNotFound, false},
+		{ErrPartial{Full: ErrUnknownValue}, ErrUnknownValue, true},
+		{ErrPartial{Full: ErrValueNotFound}, ErrUnknownValue, false},
+		{ErrPartial{State: fmt.Errorf("t1: %w", ErrValueNotFound)}, ErrValueNotFound, true},
+		{Errors{nil, ErrPartial{Voltage: ErrUnknownValue}}, ErrUnknownValue, true},
+		{Errors{ErrFatal{ErrNotFound}}, ErrNotFound, true},
+		{Errors{nil, ErrPartial{}}, ErrUnknownValue, false},
+	}
+
+	for i, c := range cases {
+		is := errors.Is(c.in, c.target)
+
+		if is != c.is {
+			t.Errorf("%d: %v != %v", i, is, c.is)
+		}
+	}
+}
+
+func TestErrorsAs(t *testing.T) {
+	var perr ErrPartial
+	if !errors.As(Errors{nil, ErrPartial{Full: errors.New("t1")}}, &perr) {
+		t.Fatal("ErrPartial not found in Errors")
+	}
+	if perr.Full == nil || perr.Full.Error() != "t1" {
+		t.Errorf("%v != t1", perr.Full)
+	}
+
+	var errno testErrno
+	if !errors.As(ErrPartial{Current: testErrno(2)}, &errno) || errno != 2 {
+		t.Errorf("%v != 2", errno)
+	}
+}
+
+type testErrno int
+
+func (e testErrno) Error() string {
+	return fmt.Sprintf("t%d", int(e))
+}
+
+func TestErrPartialFields(t *testing.T) {
+	var p ErrPartial
+	p.Set(FieldFull, errors.New("t1"))
+	p.Set(FieldState, errors.New("t2"))
+
+	if p.Full == nil || p.Full.Error() != "t1" {
+		t.Errorf("%v != t1", p.Full)
+	}
+	if err := p.Get(FieldState); err == nil || err.Error() != "t2" {
+		t.Errorf("%v != t2", err)
+	}
+	if err := p.Get(FieldCurrent); err != nil {
+		t.Errorf("%v != nil", err)
+	}
+	if err := p.Get(fieldCount); err != nil {
+		t.Errorf("%v != nil", err)
+	}
+
+	failed := p.Failed()
+	if len(failed) != 2 || failed[0] != FieldState || failed[1] != FieldFull {
+		t.Errorf("%v != [State Full]", failed)
+	}
+
+	p.Set(FieldCapacity, errors.New("t3"))
+	if p.noNil() {
+		t.Error("noNil() with only some of the fields failing")
+	}
+	for _, f := range primaryFields {
+		p.Set(f, errors.New("t4"))
+	}
+	if !p.noNil() {
+		t.Error("not noNil() with all primary fields failing")
+	}
+	p.Set(FieldCapacity, nil)
+	if p.noNil() {
+		t.Error("noNil() with only Capacity retrieved")
+	}
+}
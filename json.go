@@ -0,0 +1,369 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+func parseName(names map[string]int, kind, name string) (int, error) {
+	v, ok := names[name]
+	if !ok {
+		return 0, fmt.Errorf("battery: unknown %s %q", kind, name)
+	}
+	return v, nil
+}
+
+var stateNames = func() map[string]int {
+	m := make(map[string]int, len(states))
+	for s, name := range states {
+		m[name] = int(s)
+	}
+	return m
+}()
+
+// MarshalText encodes the state as its name, e.g. "Charging".
+func (s AgnosticState) MarshalText() ([]byte, error) {
+	if _, ok := states[s]; !ok {
+		return nil, fmt.Errorf("battery: invalid state %d", s)
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state name, as produced by MarshalText.
+func (s *AgnosticState) UnmarshalText(text []byte) error {
+	v, err := parseName(stateNames, "state", string(text))
+	if err != nil {
+		return err
+	}
+	*s = AgnosticState(v)
+	return nil
+}
+
+var kindNames = func() map[string]int {
+	m := make(map[string]int, len(kinds))
+	for k, name := range kinds {
+		m[name] = int(k)
+	}
+	return m
+}()
+
+// MarshalText encodes the kind as its name, e.g. "System".
+func (k Kind) MarshalText() ([]byte, error) {
+	if _, ok := kinds[k]; !ok {
+		return nil, fmt.Errorf("battery: invalid kind %d", k)
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a kind name, as produced by MarshalText.
+func (k *Kind) UnmarshalText(text []byte) error {
+	v, err := parseName(kindNames, "kind", string(text))
+	if err != nil {
+		return err
+	}
+	*k = Kind(v)
+	return nil
+}
+
+var warningKindNames = func() map[string]int {
+	m := make(map[string]int, len(warningKinds))
+	for k, name := range warningKinds {
+		m[name] = int(k)
+	}
+	return m
+}()
+
+// MarshalText encodes the warning kind as its name, e.g. "ZeroVoltage".
+func (k WarningKind) MarshalText() ([]byte, error) {
+	if _, ok := warningKinds[k]; !ok {
+		return nil, fmt.Errorf("battery: invalid warning kind %d", k)
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a warning kind name, as produced by MarshalText.
+func (k *WarningKind) UnmarshalText(text []byte) error {
+	v, err := parseName(warningKindNames, "warning kind", string(text))
+	if err != nil {
+		return err
+	}
+	*k = WarningKind(v)
+	return nil
+}
+
+var chemistryNames = func() map[string]int {
+	m := make(map[string]int, len(chemistries))
+	for c, name := range chemistries {
+		m[name] = int(c)
+	}
+	return m
+}()
+
+// MarshalText encodes the chemistry as its name, e.g. "LiIon".
+func (c Chemistry) MarshalText() ([]byte, error) {
+	if _, ok := chemistries[c]; !ok {
+		return nil, fmt.Errorf("battery: invalid chemistry %d", c)
+	}
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText decodes a chemistry name, as produced by MarshalText.
+func (c *Chemistry) UnmarshalText(text []byte) error {
+	v, err := parseName(chemistryNames, "chemistry", string(text))
+	if err != nil {
+		return err
+	}
+	*c = Chemistry(v)
+	return nil
+}
+
+type stateJSON struct {
+	Raw      AgnosticState
+	Specific string
+	Inferred bool `json:",omitempty"`
+}
+
+// MarshalJSON encodes the state as {"Raw": "<name>", "Specific": "<Explain()>"},
+// with "Inferred": true added for an inferred state.
+func (s State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stateJSON{s.Raw, s.specific, s.Inferred})
+}
+
+// UnmarshalJSON decodes the state, as produced by MarshalJSON.
+func (s *State) UnmarshalJSON(data []byte) error {
+	var sj stateJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return err
+	}
+	s.Raw, s.specific, s.Inferred = sj.Raw, sj.Specific, sj.Inferred
+	return nil
+}
+
+var fieldNamesIdx = func() map[string]int {
+	m := make(map[string]int, fieldCount)
+	for f, name := range fieldNames {
+		m[name] = f
+	}
+	return m
+}()
+
+// MarshalText encodes the field as its name, e.g. "ChargeRate".
+func (f Field) MarshalText() ([]byte, error) {
+	if f < 0 || f >= fieldCount {
+		return nil, fmt.Errorf("battery: invalid field %d", f)
+	}
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText decodes a field name, as produced by MarshalText.
+func (f *Field) UnmarshalText(text []byte) error {
+	v, err := parseName(fieldNamesIdx, "field", string(text))
+	if err != nil {
+		return err
+	}
+	*f = Field(v)
+	return nil
+}
+
+var originNames = func() map[string]int {
+	m := make(map[string]int, len(origins))
+	for o, name := range origins {
+		m[name] = int(o)
+	}
+	return m
+}()
+
+// MarshalText encodes the origin as its name, e.g. "Derived".
+func (o Origin) MarshalText() ([]byte, error) {
+	if _, ok := origins[o]; !ok {
+		return nil, fmt.Errorf("battery: invalid origin %d", o)
+	}
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText decodes an origin name, as produced by MarshalText.
+func (o *Origin) UnmarshalText(text []byte) error {
+	v, err := parseName(originNames, "origin", string(text))
+	if err != nil {
+		return err
+	}
+	*o = Origin(v)
+	return nil
+}
+
+// sentinels are decoded back to the same values they were encoded from.
+var sentinels = []error{
+	ErrNotFound,
+	ErrUnknownValue,
+	ErrValueNotFound,
+	ErrUnsupported,
+	ErrZeroValue,
+	ErrNotApplicable,
+	ErrAllNotNil,
+	context.Canceled,
+	context.DeadlineExceeded,
+}
+
+func messageError(msg string) error {
+	for _, s := range sentinels {
+		if s.Error() == msg {
+			return s
+		}
+	}
+	return errors.New(msg)
+}
+
+func marshalError(err error) ([]byte, error) {
+	switch err.(type) {
+	case nil:
+		return []byte("null"), nil
+	case ErrPartial, ErrFatal, Errors:
+		return json.Marshal(err)
+	}
+	return json.Marshal(err.Error())
+}
+
+// UnmarshalError decodes any error produced by this package,
+// as encoded by json.Marshal, back to its original type and stores it in err.
+//
+// Errors other than the exported sentinels (and context errors)
+// are decoded as plain errors with the same message.
+func UnmarshalError(data []byte, err *error) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0:
+		return errors.New("battery: empty error data")
+	case bytes.Equal(data, []byte("null")):
+		*err = nil
+		return nil
+	case data[0] == '"':
+		var msg string
+		if jerr := json.Unmarshal(data, &msg); jerr != nil {
+			return jerr
+		}
+		*err = messageError(msg)
+		return nil
+	case data[0] == '[':
+		var errs Errors
+		if jerr := json.Unmarshal(data, &errs); jerr != nil {
+			return jerr
+		}
+		*err = errs
+		return nil
+	}
+
+	var obj map[string]json.RawMessage
+	if jerr := json.Unmarshal(data, &obj); jerr != nil {
+		return jerr
+	}
+	if _, ok := obj["Fatal"]; ok {
+		var f ErrFatal
+		if jerr := json.Unmarshal(data, &f); jerr != nil {
+			return jerr
+		}
+		*err = f
+		return nil
+	}
+	var p ErrPartial
+	if jerr := json.Unmarshal(data, &p); jerr != nil {
+		return jerr
+	}
+	*err = p
+	return nil
+}
+
+// MarshalJSON encodes the error as {"Fatal": <error>}.
+func (f ErrFatal) MarshalJSON() ([]byte, error) {
+	inner, err := marshalError(f.Err)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]json.RawMessage{"Fatal": inner})
+}
+
+// UnmarshalJSON decodes the error, as produced by MarshalJSON.
+func (f *ErrFatal) UnmarshalJSON(data []byte) error {
+	var obj struct{ Fatal json.RawMessage }
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	if obj.Fatal == nil {
+		return errors.New("battery: missing Fatal key")
+	}
+	return UnmarshalError(obj.Fatal, &f.Err)
+}
+
+// MarshalJSON encodes the error as an object with failed field names
+// as keys and error messages as values, e.g. {"Voltage": "Not supported"}.
+func (p ErrPartial) MarshalJSON() ([]byte, error) {
+	obj := make(map[Field]string)
+	for _, f := range p.Failed() {
+		obj[f] = p.Get(f).Error()
+	}
+	return json.Marshal(obj)
+}
+
+// UnmarshalJSON decodes the error, as produced by MarshalJSON.
+func (p *ErrPartial) UnmarshalJSON(data []byte) error {
+	var obj map[Field]string
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*p = ErrPartial{}
+	for f, msg := range obj {
+		p.Set(f, messageError(msg))
+	}
+	return nil
+}
+
+// MarshalJSON encodes the errors as an array, with null for nil entries.
+func (e Errors) MarshalJSON() ([]byte, error) {
+	items := make([]json.RawMessage, len(e))
+	for i, err := range e {
+		var merr error
+		if items[i], merr = marshalError(err); merr != nil {
+			return nil, merr
+		}
+	}
+	return json.Marshal(items)
+}
+
+// UnmarshalJSON decodes the errors, as produced by MarshalJSON.
+func (e *Errors) UnmarshalJSON(data []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	errs := make(Errors, len(items))
+	for i, item := range items {
+		if err := UnmarshalError(item, &errs[i]); err != nil {
+			return err
+		}
+	}
+	*e = errs
+	return nil
+}
@@ -0,0 +1,137 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestBatteryJSON(t *testing.T) {
+	b := &Battery{
+		ID:            "BAT0",
+		Name:          "5B10W13930",
+		Kind:          KindSystem,
+		State:         State{Raw: Charging, specific: "Charging"},
+		Capacity:      75,
+		Current:       45000,
+		Full:          60000,
+		Design:        62000,
+		ChargeRate:    12000,
+		Voltage:       11.8,
+		DesignVoltage: 11.4,
+		NetPower:      12000,
+		Provenance: map[Field]Provenance{
+			FieldCurrent:       {Derived, "charge_now, voltage_now"},
+			FieldDesignVoltage: {Copied, "voltage_now"},
+		},
+		Attributes: map[string]string{"POWER_SUPPLY_TECHNOLOGY": "Li-poly"},
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("%v != nil", err)
+	}
+	want := `{"ID":"BAT0","Name":"5B10W13930","Kind":"System","State":{"Raw":"Charging","Specific":"Charging"},` +
+		`"Capacity":75,"Current":45000,"Full":60000,"Design":62000,"ChargeRate":12000,"Voltage":11.8,"DesignVoltage":11.4,"NetPower":12000,` +
+		`"ChargeNow":0,"ChargeFull":0,"ChargeDesign":0,"CurrentNow":0,"FirmwareTimeToEmpty":0,"FirmwareTimeToFull":0,"AverageChargeRate":0,"CycleCount":0,"Temperature":0,` +
+		`"Manufacturer":"","Model":"","Serial":"","Chemistry":"Unknown","ManufactureDate":"0001-01-01T00:00:00Z",` +
+		`"Provenance":{"Current":{"Origin":"Derived","Source":"charge_now, voltage_now"},` +
+		`"DesignVoltage":{"Origin":"Copied","Source":"voltage_now"}},` +
+		`"Attributes":{"POWER_SUPPLY_TECHNOLOGY":"Li-poly"}}`
+	if string(data) != want {
+		t.Errorf("%s != %s", data, want)
+	}
+
+	var out *Battery
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("%v != nil", err)
+	}
+	if !reflect.DeepEqual(out, b) {
+		t.Errorf("%v != %v", out, b)
+	}
+}
+
+func TestAgnosticStateText(t *testing.T) {
+	for _, s := range []AgnosticState{Undefined, Unknown, Empty, Full, Charging, Discharging, Idle} {
+		text, err := s.MarshalText()
+		if err != nil {
+			t.Fatalf("%v: %v != nil", s, err)
+		}
+		var out AgnosticState
+		if err := out.UnmarshalText(text); err != nil || out != s {
+			t.Errorf("%v: %v, %v != %v, nil", s, out, err, s)
+		}
+	}
+
+	var out AgnosticState
+	if err := out.UnmarshalText([]byte("Exploded")); err == nil {
+		t.Error("nil == nil")
+	}
+}
+
+func TestErrorsJSON(t *testing.T) {
+	cases := []struct {
+		errorIn  error
+		json     string
+		errorOut error
+	}{{
+		nil, `null`, nil,
+	}, {
+		ErrFatal{ErrAllNotNil},
+		`{"Fatal":"All fields had not nil errors"}`,
+		ErrFatal{ErrAllNotNil},
+	}, {
+		ErrFatal{context.DeadlineExceeded},
+		`{"Fatal":"context deadline exceeded"}`,
+		ErrFatal{context.DeadlineExceeded},
+	}, {
+		ErrPartial{Voltage: ErrUnsupported, Full: fmt.Errorf("t1")},
+		`{"Full":"t1","Voltage":"Not supported"}`,
+		ErrPartial{Voltage: ErrUnsupported, Full: fmt.Errorf("t1")},
+	}, {
+		Errors{nil, ErrPartial{State: ErrUnknownValue}, ErrFatal{fmt.Errorf("t2")}, fmt.Errorf("t3")},
+		`[null,{"State":"Unknown value received"},{"Fatal":"t2"},"t3"]`,
+		Errors{nil, ErrPartial{State: ErrUnknownValue}, ErrFatal{fmt.Errorf("t2")}, fmt.Errorf("t3")},
+	}}
+
+	for i, c := range cases {
+		data, err := json.Marshal(c.errorIn)
+		if err != nil {
+			t.Fatalf("%d: %v != nil", i, err)
+		}
+		if string(data) != c.json {
+			t.Errorf("%d: %s != %s", i, data, c.json)
+		}
+
+		var out error
+		if err := UnmarshalError(data, &out); err != nil {
+			t.Fatalf("%d: %v != nil", i, err)
+		}
+		if !reflect.DeepEqual(out, c.errorOut) {
+			t.Errorf("%d: %#v != %#v", i, out, c.errorOut)
+		}
+	}
+}
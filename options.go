@@ -0,0 +1,100 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"context"
+)
+
+// Conversion type selects the voltage used to convert charge (in mAh) to energy (in mWh)
+// and back, for systems that report one but not the other.
+type Conversion int8
+
+const (
+	// ConvertInstantaneous uses the current voltage for all the values,
+	// except for the design ones, that use the design voltage.
+	ConvertInstantaneous Conversion = iota
+	// ConvertDesign uses the design voltage for all the values.
+	// Energy values do not drift as the voltage sags during discharge.
+	ConvertDesign
+	// ConvertNominal uses Options.NominalVoltage for all the values.
+	ConvertNominal
+)
+
+// Options type customizes the way battery information is retrieved.
+//
+// Zero value means the defaults, as used by Get and GetAll.
+type Options struct {
+	// Root of the sysfs tree read by the Linux backend (defaults to "/sys").
+	// Useful when host's /sys is mounted elsewhere, e.g. inside a container.
+	SysfsRoot string
+	// Fill Battery.Attributes with the raw key/value pairs reported by the system.
+	Attributes bool
+	// Voltage used for charge/energy conversion (defaults to ConvertInstantaneous).
+	Conversion Conversion
+	// Fixed voltage (in V) used with ConvertNominal.
+	// If it is not positive, the design voltage is used instead.
+	NominalVoltage float64
+	// Fill AverageChargeRate by averaging ChargeRate over the calls sharing
+	// the same Averager, for batteries the system does not provide it for.
+	Averager *Averager
+	// What is done about implausible readings (defaults to ValidateReport).
+	Validation Validation
+	// Quirks applied in addition to the built-in ones, e.g. read with LoadQuirks.
+	Quirks []Quirk
+	// Do not apply the built-in quirks.
+	NoBuiltinQuirks bool
+	// Kinds of the batteries to return, e.g. only KindSystem. All of them if empty.
+	Kinds []Kind
+}
+
+// accepts reports whether b is of one of the requested kinds.
+func (opts Options) accepts(b *Battery) bool {
+	if len(opts.Kinds) == 0 {
+		return true
+	}
+	if b == nil {
+		return false
+	}
+	for _, k := range opts.Kinds {
+		if b.Kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWithOptions is like GetContext, but uses given options.
+func GetWithOptions(ctx context.Context, idx int, opts Options) (*Battery, error) {
+	b, err := get(func(idx int) (*Battery, error) {
+		return registeredGet(ctx, idx, opts)
+	}, idx)
+	return b, contextError(ctx, err)
+}
+
+// GetAllWithOptions is like GetAllContext, but uses given options.
+func GetAllWithOptions(ctx context.Context, opts Options) ([]*Battery, error) {
+	bs, err := getAll(func() ([]*Battery, error) {
+		return registeredGetAll(ctx, opts)
+	})
+	return bs, contextError(ctx, err)
+}
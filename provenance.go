@@ -0,0 +1,83 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import "strings"
+
+// Origin type enumerates the ways a field value can be obtained.
+type Origin int8
+
+const (
+	// Measured specifies a value read directly from the system.
+	Measured Origin = iota
+	// Derived specifies a value calculated from other values,
+	// e.g. charge (in mAh) converted to energy using voltage.
+	Derived
+	// Copied specifies a value taken from another field as a fallback,
+	// e.g. DesignVoltage being a copy of Voltage.
+	Copied
+)
+
+var origins = map[Origin]string{
+	Measured: "Measured",
+	Derived:  "Derived",
+	Copied:   "Copied",
+}
+
+func (o Origin) String() string {
+	return origins[o]
+}
+
+// Provenance type describes where a field value comes from.
+type Provenance struct {
+	Origin Origin
+	// System specific source of the value, e.g. sysfs file path, ioctl name or plist key.
+	// Comma separated list of sources for Derived values.
+	Source string
+}
+
+func (p Provenance) String() string {
+	return p.Origin.String() + " (" + p.Source + ")"
+}
+
+// setSource records provenance of field f, if it was retrieved without an error.
+// Otherwise any provenance recorded previously is removed.
+func (b *Battery) setSource(f Field, err error, o Origin, sources ...string) {
+	if err != nil {
+		delete(b.Provenance, f)
+		return
+	}
+	if b.Provenance == nil {
+		b.Provenance = make(map[Field]Provenance)
+	}
+	var known []string
+	for _, s := range sources {
+		if s != "" {
+			known = append(known, s)
+		}
+	}
+	b.Provenance[f] = Provenance{o, strings.Join(known, ", ")}
+}
+
+func (b *Battery) source(f Field) string {
+	return b.Provenance[f].Source
+}
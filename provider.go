@@ -0,0 +1,272 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Provider is a source of battery information.
+//
+// Implementations follow the same rules as the built-in system backend:
+// Get returns ErrNotFound for an index past the last battery and ErrPartial
+// if only some of the values could be retrieved, GetAll returns Errors
+// with one entry per returned battery. Errors do not have to be wrapped
+// in ErrFatal, that is done by the package level functions.
+type Provider interface {
+	Get(idx int) (*Battery, error)
+	GetAll() ([]*Battery, error)
+}
+
+// ContextProvider is a Provider that supports cancellation and deadlines.
+//
+// Providers not implementing it are abandoned when the context is done,
+// but their calls keep running in the background until they return on their own.
+type ContextProvider interface {
+	Provider
+	GetContext(ctx context.Context, idx int) (*Battery, error)
+	GetAllContext(ctx context.Context) ([]*Battery, error)
+}
+
+// SystemProvider is the name under which the operating system backend is registered.
+const SystemProvider = "system"
+
+// System is the operating system backend. It can be used to register
+// the backend again after it was unregistered, e.g. to change the order.
+var System Provider = systemProvider{}
+
+type systemProvider struct{}
+
+func (systemProvider) Get(idx int) (*Battery, error) {
+	return systemGet(context.Background(), idx, Options{})
+}
+
+func (systemProvider) GetAll() ([]*Battery, error) {
+	return systemGetAll(context.Background(), Options{})
+}
+
+func (systemProvider) GetContext(ctx context.Context, idx int) (*Battery, error) {
+	return systemGet(ctx, idx, Options{})
+}
+
+func (systemProvider) GetAllContext(ctx context.Context) ([]*Battery, error) {
+	return systemGetAll(ctx, Options{})
+}
+
+func (systemProvider) getOptions(ctx context.Context, idx int, opts Options) (*Battery, error) {
+	return systemGet(ctx, idx, opts)
+}
+
+func (systemProvider) getAllOptions(ctx context.Context, opts Options) ([]*Battery, error) {
+	return systemGetAll(ctx, opts)
+}
+
+// optionsProvider is implemented by built-in providers that understand Options.
+type optionsProvider interface {
+	getOptions(ctx context.Context, idx int, opts Options) (*Battery, error)
+	getAllOptions(ctx context.Context, opts Options) ([]*Battery, error)
+}
+
+func providerGet(ctx context.Context, p Provider, idx int, opts Options) (*Battery, error) {
+	if op, ok := p.(optionsProvider); ok {
+		return op.getOptions(ctx, idx, opts)
+	}
+	if cp, ok := p.(ContextProvider); ok {
+		return cp.GetContext(ctx, idx)
+	}
+	var b *Battery
+	var err error
+	if cerr := withContext(ctx, func() { b, err = p.Get(idx) }); cerr != nil {
+		return nil, cerr
+	}
+	return b, err
+}
+
+func providerGetAll(ctx context.Context, p Provider, opts Options) ([]*Battery, error) {
+	if op, ok := p.(optionsProvider); ok {
+		return op.getAllOptions(ctx, opts)
+	}
+	if cp, ok := p.(ContextProvider); ok {
+		return cp.GetAllContext(ctx)
+	}
+	var bs []*Battery
+	var err error
+	if cerr := withContext(ctx, func() { bs, err = p.GetAll() }); cerr != nil {
+		return nil, cerr
+	}
+	return bs, err
+}
+
+type provider struct {
+	name string
+	p    Provider
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = []provider{{SystemProvider, System}}
+)
+
+// Register makes a provider available under given name.
+//
+// Batteries are returned in provider registration order, the system backend
+// being registered first under the SystemProvider name.
+//
+// If Register is called twice with the same name or if p is nil, it panics.
+func Register(name string, p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if p == nil {
+		panic("battery: Register provider is nil")
+	}
+	for _, pr := range providers {
+		if pr.name == name {
+			panic(fmt.Sprintf("battery: Register called twice for provider %q", name))
+		}
+	}
+	providers = append(providers, provider{name, p})
+}
+
+// Unregister removes provider registered under given name.
+// It does nothing if there is no such provider.
+func Unregister(name string) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	for i, pr := range providers {
+		if pr.name == name {
+			providers = append(providers[:i:i], providers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Providers returns names of all registered providers, in order.
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, len(providers))
+	for i, pr := range providers {
+		names[i] = pr.name
+	}
+	return names
+}
+
+func registered() []provider {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	return append([]provider(nil), providers...)
+}
+
+func registeredGet(ctx context.Context, idx int, opts Options) (*Battery, error) {
+	return providersGet(ctx, registered(), idx, opts)
+}
+
+func registeredGetAll(ctx context.Context, opts Options) ([]*Battery, error) {
+	return providersGetAll(ctx, registered(), opts)
+}
+
+func registeredSamples(ctx context.Context, opts Options) ([]Sample, error) {
+	return providersSamples(ctx, registered(), opts)
+}
+
+// Index is normalized across providers, so that it matches
+// the position of the battery in merged GetAll results.
+func providersGet(ctx context.Context, ps []provider, idx int, opts Options) (*Battery, error) {
+	if len(ps) == 1 && len(opts.Kinds) == 0 {
+		b, err := providerGet(ctx, ps[0].p, idx, opts)
+		if opts.Averager != nil {
+			opts.Averager.update(ps[0].name, b, time.Now())
+		}
+		return b, err
+	}
+
+	// Otherwise the index can only be told after getting all of the batteries,
+	// skipping failed providers and filtered out batteries the same way.
+	samples, err := providersSamples(ctx, ps, opts)
+	errs, isErrors := err.(Errors)
+	if err != nil && !isErrors {
+		return nil, err
+	}
+	if idx < 0 || idx >= len(samples) {
+		return nil, ErrNotFound
+	}
+	if idx < len(errs) {
+		return samples[idx].Battery, errs[idx]
+	}
+	return samples[idx].Battery, nil
+}
+
+func providersGetAll(ctx context.Context, ps []provider, opts Options) ([]*Battery, error) {
+	samples, err := providersSamples(ctx, ps, opts)
+	return sampleBatteries(samples), err
+}
+
+// Provider that failed completely is only reported if no other
+// provider returned any batteries, otherwise it is skipped.
+func providersSamples(ctx context.Context, ps []provider, opts Options) ([]Sample, error) {
+	var samples []Sample
+	var errors Errors
+	var fatal error
+	for _, pr := range ps {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		bs, err := providerGetAll(ctx, pr.p, opts)
+		duration := time.Since(start)
+		errs, isErrors := err.(Errors)
+		if !isErrors && err != nil && len(bs) == 0 {
+			if fatal == nil {
+				fatal = err
+			}
+			continue
+		}
+		for i, b := range bs {
+			var berr error
+			switch {
+			case !isErrors:
+				berr = err
+			case i < len(errs):
+				berr = errs[i]
+			}
+			if !opts.accepts(b) {
+				continue
+			}
+			if opts.Averager != nil {
+				opts.Averager.update(pr.name, b, start)
+			}
+			samples = append(samples, Sample{b, start, duration, pr.name})
+			errors = append(errors, berr)
+		}
+	}
+	if len(samples) == 0 && fatal != nil {
+		return nil, fatal
+	}
+	return samples, errors
+}
@@ -0,0 +1,265 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	batteries []*Battery
+	err       error
+}
+
+func (f fakeProvider) Get(idx int) (*Battery, error) {
+	if f.err != nil {
+		if errs, ok := f.err.(Errors); !ok {
+			return nil, f.err
+		} else if idx < len(errs) && idx < len(f.batteries) {
+			return f.batteries[idx], errs[idx]
+		}
+	}
+	if idx >= len(f.batteries) {
+		return nil, ErrNotFound
+	}
+	return f.batteries[idx], nil
+}
+
+func (f fakeProvider) GetAll() ([]*Battery, error) {
+	return f.batteries, f.err
+}
+
+// blockingProvider blocks until done is closed.
+type blockingProvider struct {
+	done chan struct{}
+}
+
+func (p blockingProvider) Get(idx int) (*Battery, error) {
+	<-p.done
+	return nil, ErrNotFound
+}
+
+func (p blockingProvider) GetAll() ([]*Battery, error) {
+	<-p.done
+	return nil, nil
+}
+
+func TestProvidersContext(t *testing.T) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	ps := []provider{{"p1", blockingProvider{done}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := providersGet(ctx, ps, 0, Options{}); err != context.DeadlineExceeded {
+		t.Errorf("%v != %v", err, context.DeadlineExceeded)
+	}
+	if _, err := providersGetAll(ctx, ps, Options{}); err != context.DeadlineExceeded {
+		t.Errorf("%v != %v", err, context.DeadlineExceeded)
+	}
+	if err := contextError(ctx, ErrFatal{ErrAllNotNil}); err != (ErrFatal{context.DeadlineExceeded}) {
+		t.Errorf("%v != %v", err, ErrFatal{context.DeadlineExceeded})
+	}
+}
+
+func TestProvidersGet(t *testing.T) {
+	ps := []provider{
+		{"p1", fakeProvider{[]*Battery{{Full: 1}, {Full: 2}}, nil}},
+		{"p2", fakeProvider{nil, nil}},
+		{"p3", fakeProvider{[]*Battery{{Full: 3}}, Errors{ErrPartial{Full: fmt.Errorf("t1")}}}},
+	}
+	cases := []struct {
+		idx        int
+		batteryOut *Battery
+		errorOut   error
+	}{
+		{0, &Battery{Full: 1}, nil},
+		{1, &Battery{Full: 2}, nil},
+		{2, &Battery{Full: 3}, ErrPartial{Full: fmt.Errorf("t1")}},
+		{3, nil, ErrNotFound},
+	}
+
+	for i, c := range cases {
+		battery, err := providersGet(context.Background(), ps, c.idx, Options{})
+
+		if !reflect.DeepEqual(battery, c.batteryOut) {
+			t.Errorf("%d: %v != %v", i, battery, c.batteryOut)
+		}
+		if !reflect.DeepEqual(err, c.errorOut) {
+			t.Errorf("%d: %v != %v", i, err, c.errorOut)
+		}
+	}
+
+	// Failed provider is skipped, same as by GetAll.
+	ps = []provider{
+		{"p1", fakeProvider{nil, fmt.Errorf("t2")}},
+		{"p2", fakeProvider{[]*Battery{{Full: 4}}, nil}},
+	}
+	battery, err := providersGet(context.Background(), ps, 0, Options{})
+	if want := (&Battery{Full: 4}); !reflect.DeepEqual(battery, want) || err != nil {
+		t.Errorf("%v, %v != %v, <nil>", battery, err, want)
+	}
+}
+
+func TestProvidersGetAll(t *testing.T) {
+	cases := []struct {
+		providersIn  []provider
+		batteriesOut []*Battery
+		errorsOut    error
+	}{{
+		[]provider{{"p1", fakeProvider{[]*Battery{{Full: 1}}, nil}}},
+		[]*Battery{{Full: 1}}, Errors{nil},
+	}, {
+		[]provider{
+			{"p1", fakeProvider{[]*Battery{{Full: 2}}, Errors{ErrPartial{Full: fmt.Errorf("t1")}}}},
+			{"p2", fakeProvider{[]*Battery{{Full: 3}, {Full: 4}}, nil}},
+		},
+		[]*Battery{{Full: 2}, {Full: 3}, {Full: 4}}, Errors{ErrPartial{Full: fmt.Errorf("t1")}, nil, nil},
+	}, {
+		[]provider{
+			{"p1", fakeProvider{nil, fmt.Errorf("t2")}},
+			{"p2", fakeProvider{[]*Battery{{Full: 5}}, nil}},
+		},
+		[]*Battery{{Full: 5}}, Errors{nil},
+	}, {
+		[]provider{
+			{"p1", fakeProvider{nil, fmt.Errorf("t3")}},
+			{"p2", fakeProvider{nil, fmt.Errorf("t4")}},
+		},
+		nil, fmt.Errorf("t3"),
+	}}
+
+	for i, c := range cases {
+		batteries, err := providersGetAll(context.Background(), c.providersIn, Options{})
+
+		if !reflect.DeepEqual(batteries, c.batteriesOut) {
+			t.Errorf("%d: %v != %v", i, batteries, c.batteriesOut)
+		}
+		if !reflect.DeepEqual(err, c.errorsOut) {
+			t.Errorf("%d: %v != %v", i, err, c.errorsOut)
+		}
+	}
+}
+
+func TestProvidersKinds(t *testing.T) {
+	ps := []provider{
+		{"p1", fakeProvider{[]*Battery{{Kind: KindSystem, Full: 1}, {Kind: KindPeripheral, Full: 2}}, nil}},
+		{"p2", fakeProvider{[]*Battery{{Kind: KindUPS, Full: 3}, {Kind: KindSystem, Full: 4}}, Errors{nil, ErrPartial{Full: fmt.Errorf("t1")}}}},
+	}
+	opts := Options{Kinds: []Kind{KindSystem}}
+
+	batteries, err := providersGetAll(context.Background(), ps, opts)
+	if want := []*Battery{{Kind: KindSystem, Full: 1}, {Kind: KindSystem, Full: 4}}; !reflect.DeepEqual(batteries, want) {
+		t.Errorf("%v != %v", batteries, want)
+	}
+	if want := (Errors{nil, ErrPartial{Full: fmt.Errorf("t1")}}); !reflect.DeepEqual(err, want) {
+		t.Errorf("%v != %v", err, want)
+	}
+
+	b, err := providersGet(context.Background(), ps, 1, opts)
+	if want := (&Battery{Kind: KindSystem, Full: 4}); !reflect.DeepEqual(b, want) || !reflect.DeepEqual(err, ErrPartial{Full: fmt.Errorf("t1")}) {
+		t.Errorf("%v, %v != %v, t1", b, err, want)
+	}
+	if _, err := providersGet(context.Background(), ps, 2, opts); err != ErrNotFound {
+		t.Errorf("%v != %v", err, ErrNotFound)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("test", fakeProvider{})
+	defer Unregister("test")
+
+	names := Providers()
+	if !reflect.DeepEqual(names, []string{SystemProvider, "test"}) {
+		t.Errorf("%v != %v", names, []string{SystemProvider, "test"})
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("duplicate Register did not panic")
+			}
+		}()
+		Register("test", fakeProvider{})
+	}()
+
+	Unregister("test")
+	names = Providers()
+	if !reflect.DeepEqual(names, []string{SystemProvider}) {
+		t.Errorf("%v != %v", names, []string{SystemProvider})
+	}
+}
+
+func TestRegisterSystem(t *testing.T) {
+	Register("test", fakeProvider{})
+	Unregister(SystemProvider)
+	Register(SystemProvider, System)
+	defer func() {
+		Unregister(SystemProvider)
+		Unregister("test")
+		Register(SystemProvider, System)
+	}()
+
+	names := Providers()
+	if !reflect.DeepEqual(names, []string{"test", SystemProvider}) {
+		t.Errorf("%v != %v", names, []string{"test", SystemProvider})
+	}
+}
+
+func TestProvidersSamples(t *testing.T) {
+	ps := []provider{
+		{"p1", fakeProvider{[]*Battery{{Full: 1}}, nil}},
+		{"p2", fakeProvider{nil, fmt.Errorf("t1")}},
+		{"p3", fakeProvider{[]*Battery{{Full: 2}, {Full: 3}}, nil}},
+	}
+
+	before := time.Now()
+	samples, err := providersSamples(context.Background(), ps, Options{})
+	after := time.Now()
+
+	if !reflect.DeepEqual(err, Errors{nil, nil, nil}) {
+		t.Errorf("%v != %v", err, Errors{nil, nil, nil})
+	}
+	backends := []string{"p1", "p3", "p3"}
+	if len(samples) != len(backends) {
+		t.Fatalf("%d != %d", len(samples), len(backends))
+	}
+	for i, s := range samples {
+		if s.Backend != backends[i] {
+			t.Errorf("%d: %v != %v", i, s.Backend, backends[i])
+		}
+		if s.Full != float64(i+1) {
+			t.Errorf("%d: %v != %v", i, s.Full, i+1)
+		}
+		if s.Time.Before(before) || s.Time.Add(s.Duration).After(after) {
+			t.Errorf("%d: %v+%v outside of [%v, %v]", i, s.Time, s.Duration, before, after)
+		}
+	}
+	if samples[1].Time != samples[2].Time {
+		t.Errorf("%v != %v", samples[1].Time, samples[2].Time)
+	}
+}
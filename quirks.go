@@ -0,0 +1,192 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+)
+
+// Quirk describes a known firmware bug and the way to correct it.
+//
+// Quirks are applied to the values read by the operating system backend,
+// before they are validated. All the matching quirks are applied, in order.
+type Quirk struct {
+	// Name identifying the quirk in Battery.Quirks.
+	Name string
+	// Backend (runtime.GOOS value, e.g. "linux"), manufacturer and model
+	// the quirk applies to, compared ignoring case.
+	// Empty ones match any battery.
+	Backend      string `json:",omitempty"`
+	Manufacturer string `json:",omitempty"`
+	Model        string `json:",omitempty"`
+	// Values the fields need to have for the quirk to apply.
+	If map[Field]float64 `json:",omitempty"`
+
+	// Factors the fields are multiplied by, e.g. 0.001 for values reported in wrong units.
+	Scale map[Field]float64 `json:",omitempty"`
+	// Energy fields holding charge (in mAh) or current (in mA) instead,
+	// e.g. ChargeRate for power_now reported in µA.
+	Charge []Field `json:",omitempty"`
+	// Fields with bogus values, dropped with ErrUnknownValue.
+	// Ignoring State makes it Unknown, so that it is inferred from the other values.
+	Ignore []Field `json:",omitempty"`
+}
+
+// builtinQuirks apply to any battery reporting the values,
+// model specific ones can be added with Options.Quirks.
+var builtinQuirks = []Quirk{{
+	Name:   "zero-design-voltage",
+	If:     map[Field]float64{FieldDesignVoltage: 0},
+	Ignore: []Field{FieldDesignVoltage},
+}, {
+	Name:   "zero-design-charge",
+	If:     map[Field]float64{FieldChargeDesign: 0},
+	Ignore: []Field{FieldChargeDesign, FieldDesign},
+}, {
+	Name:   "zero-design-energy",
+	If:     map[Field]float64{FieldDesign: 0},
+	Ignore: []Field{FieldDesign},
+}}
+
+// LoadQuirks reads quirks from a JSON file, holding an array of Quirk objects, e.g.:
+//
+//	[{"Name": "power-in-current", "Backend": "linux", "Model": "DELL 1C75X31", "Charge": ["ChargeRate"]}]
+func LoadQuirks(path string) ([]Quirk, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var quirks []Quirk
+	if err := json.Unmarshal(data, &quirks); err != nil {
+		return nil, err
+	}
+	return quirks, nil
+}
+
+func matches(pattern, value string) bool {
+	return pattern == "" || strings.EqualFold(pattern, strings.TrimSpace(value))
+}
+
+func (q *Quirk) matches(b *Battery, backend string) bool {
+	if !matches(q.Backend, backend) || !matches(q.Manufacturer, b.Manufacturer) || !matches(q.Model, b.Model) {
+		return false
+	}
+	for f, v := range q.If {
+		p := b.float(f)
+		if p == nil || !b.has(f) || *p != v {
+			return false
+		}
+	}
+	return true
+}
+
+// quirks returns the quirks enabled by opts.
+func quirks(opts Options) []Quirk {
+	if opts.NoBuiltinQuirks {
+		return opts.Quirks
+	}
+	return append(builtinQuirks[:len(builtinQuirks):len(builtinQuirks)], opts.Quirks...)
+}
+
+// ignoresState tells whether a quirk matching b drops its State,
+// so that it has to be inferred even though the backend reported it.
+func ignoresState(b *Battery, opts Options, backend string) bool {
+	for _, q := range quirks(opts) {
+		if !q.matches(b, backend) {
+			continue
+		}
+		for _, f := range q.Ignore {
+			if f == FieldState {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyQuirks corrects the values read by given backend with the matching quirks.
+func applyQuirks(b *Battery, e *ErrPartial, opts Options, backend string) {
+	ignored := make(map[Field]bool)
+	for _, q := range quirks(opts) {
+		if !q.matches(b, backend) {
+			continue
+		}
+		b.Quirks = append(b.Quirks, q.Name)
+		q.apply(b, e, opts, ignored)
+	}
+}
+
+// apply corrects the values of b, adding the fields it drops to ignored.
+func (q *Quirk) apply(b *Battery, e *ErrPartial, opts Options, ignored map[Field]bool) {
+	for f, factor := range q.Scale {
+		if p := b.float(f); p != nil {
+			*p *= factor
+		}
+	}
+	for _, f := range q.Charge {
+		for _, c := range chargeFields {
+			if c.energy == f {
+				nativeCharge(b, e, f, c.charge, opts)
+			}
+		}
+	}
+	for _, f := range q.Ignore {
+		ignored[f] = true
+		b.ignore(f, e)
+	}
+
+	if e.DesignVoltage != nil && e.Voltage == nil {
+		b.DesignVoltage, e.DesignVoltage = b.Voltage, nil
+		b.setSource(FieldDesignVoltage, nil, Copied, b.source(FieldVoltage))
+	}
+	// Energies converted from charge might have used the corrected values,
+	// unless the quirk scaled them itself.
+	for _, c := range chargeFields {
+		if _, scaled := q.Scale[c.energy]; scaled {
+			continue
+		}
+		if p, ok := b.Provenance[c.charge]; ok && p.Origin == Measured && !ignored[c.energy] {
+			chargeToEnergy(b, e, c.energy, c.charge, opts)
+		}
+	}
+}
+
+// ignore drops the value of f, as if the system reported it as unknown.
+func (b *Battery) ignore(f Field, e *ErrPartial) {
+	switch f {
+	case FieldState:
+		b.State.Raw = Unknown
+		return
+	case FieldFirmwareTimeToEmpty:
+		b.FirmwareTimeToEmpty = 0
+	case FieldFirmwareTimeToFull:
+		b.FirmwareTimeToFull = 0
+	default:
+		if p := b.float(f); p != nil {
+			*p = 0
+		}
+	}
+	e.Set(f, ErrUnknownValue)
+	b.setSource(f, ErrUnknownValue, Measured)
+}
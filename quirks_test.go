@@ -0,0 +1,119 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyQuirks(t *testing.T) {
+	quirks, err := LoadQuirks("testdata/quirks.json")
+	if err != nil {
+		t.Fatalf("%v != nil", err)
+	}
+	measured := func(fields ...Field) map[Field]Provenance {
+		p := make(map[Field]Provenance)
+		for _, f := range fields {
+			p[f] = Provenance{Measured, f.String()}
+		}
+		return p
+	}
+
+	cases := []struct {
+		backend    string
+		batteryIn  *Battery
+		errorOut   ErrPartial
+		batteryOut *Battery
+	}{{
+		"linux",
+		&Battery{
+			Model: "DELL 1C75X31", ChargeRate: 1500, Voltage: 12, DesignVoltage: 11.4,
+			Provenance: measured(FieldChargeRate, FieldVoltage, FieldDesignVoltage),
+		},
+		ErrPartial{},
+		&Battery{
+			Model: "DELL 1C75X31", ChargeRate: 18000, Voltage: 12, DesignVoltage: 11.4, CurrentNow: 1500,
+			Provenance: map[Field]Provenance{
+				FieldChargeRate:    {Derived, "ChargeRate, Voltage"},
+				FieldVoltage:       {Measured, "Voltage"},
+				FieldDesignVoltage: {Measured, "DesignVoltage"},
+				FieldCurrentNow:    {Measured, "ChargeRate"},
+			},
+			Quirks: []string{"power-in-current"},
+		},
+	}, {
+		"windows",
+		&Battery{
+			Model: "DELL 1C75X31", ChargeRate: 1500, Voltage: 12, DesignVoltage: 11.4,
+			Provenance: measured(FieldChargeRate, FieldVoltage, FieldDesignVoltage),
+		},
+		ErrPartial{},
+		&Battery{
+			Model: "DELL 1C75X31", ChargeRate: 1500, Voltage: 12, DesignVoltage: 11.4,
+			Provenance: measured(FieldChargeRate, FieldVoltage, FieldDesignVoltage),
+		},
+	}, {
+		"darwin",
+		&Battery{
+			Manufacturer: "smp ", State: State{Raw: Charging, specific: "IsCharging"}, Current: 45000000,
+			Voltage: 12, DesignVoltage: 12,
+			Provenance: measured(FieldState, FieldCurrent, FieldVoltage, FieldDesignVoltage),
+		},
+		ErrPartial{},
+		&Battery{
+			Manufacturer: "smp ", State: State{Raw: Unknown, specific: "IsCharging"}, Current: 45000,
+			Voltage: 12, DesignVoltage: 12,
+			Provenance: measured(FieldState, FieldCurrent, FieldVoltage, FieldDesignVoltage),
+			Quirks:     []string{"stuck-status"},
+		},
+	}, {
+		"netbsd",
+		&Battery{
+			Voltage: 12, ChargeDesign: 4000,
+			Provenance: measured(FieldVoltage, FieldDesignVoltage, FieldChargeDesign, FieldDesign),
+		},
+		ErrPartial{},
+		&Battery{
+			Voltage: 12, DesignVoltage: 12, ChargeDesign: 4000, Design: 48000,
+			Provenance: map[Field]Provenance{
+				FieldVoltage:       {Measured, "Voltage"},
+				FieldDesignVoltage: {Copied, "Voltage"},
+				FieldChargeDesign:  {Measured, "ChargeDesign"},
+				FieldDesign:        {Derived, "ChargeDesign, Voltage"},
+			},
+			Quirks: []string{"zero-design-voltage"},
+		},
+	}}
+
+	for i, c := range cases {
+		e := ErrPartial{}
+		applyQuirks(c.batteryIn, &e, Options{Quirks: quirks}, c.backend)
+
+		if !reflect.DeepEqual(e, c.errorOut) {
+			t.Errorf("%d: %v != %v", i, e, c.errorOut)
+		}
+		if !reflect.DeepEqual(c.batteryIn, c.batteryOut) {
+			t.Errorf("%d: %v != %v", i, c.batteryIn, c.batteryOut)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"context"
+	"time"
+)
+
+// Sample type is a battery information together with the details of its retrieval.
+type Sample struct {
+	*Battery
+	// Time the read was started at.
+	// It carries a monotonic clock reading, so that Sub between two
+	// samples is not affected by changes of the wall clock.
+	Time time.Time
+	// How long the read took.
+	// Values were taken somewhere between Time and Time+Duration.
+	Duration time.Duration
+	// Name of the provider the battery comes from, e.g. SystemProvider.
+	Backend string
+}
+
+// Midpoint returns the middle of the read window,
+// which is the best estimate of when the values were taken.
+func (s Sample) Midpoint() time.Time {
+	return s.Time.Add(s.Duration / 2)
+}
+
+func sampleBatteries(samples []Sample) []*Battery {
+	if samples == nil {
+		return nil
+	}
+	bs := make([]*Battery, len(samples))
+	for i, s := range samples {
+		bs[i] = s.Battery
+	}
+	return bs
+}
+
+// GetAllSamples is like GetAllWithOptions, but returns timestamped samples.
+//
+// Batteries coming from the same provider share the Time and Duration values,
+// as they are retrieved in a single call.
+//
+// If error != nil, it will be either ErrFatal or Errors,
+// following the same rules as GetAll.
+func GetAllSamples(ctx context.Context, opts Options) ([]Sample, error) {
+	var samples []Sample
+	bs, err := getAll(func() ([]*Battery, error) {
+		var err error
+		samples, err = registeredSamples(ctx, opts)
+		return sampleBatteries(samples), err
+	})
+	if bs == nil {
+		samples = nil
+	}
+	return samples, contextError(ctx, err)
+}
@@ -0,0 +1,77 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+// stateHints are facts about the system, other than the battery values,
+// that help to infer the state if the system does not report it.
+type stateHints struct {
+	// Whether external power is connected, nil if not known.
+	online       *bool
+	onlineSource string
+	// Charge level (in %) at which charging stops, zero if not known.
+	threshold       float64
+	thresholdSource string
+}
+
+// onlineHints returns hints with given external power status.
+func onlineHints(online bool, source string) stateHints {
+	return stateHints{online: &online, onlineSource: source}
+}
+
+// inferState replaces Unknown or Undefined state reported by the system
+// with one inferred from the sign of the rate, the hints and the Current/Full ratio.
+// It has to be called before the rate sign is normalized.
+func inferState(b *Battery, e *ErrPartial, h stateHints) {
+	if e.State != nil || (b.State.Raw != Unknown && b.State.Raw != Undefined) {
+		return
+	}
+
+	hasRate := e.ChargeRate == nil
+	online := h.online != nil && *h.online
+	offline := h.online != nil && !*h.online
+	atFull := e.Current == nil && e.Full == nil && b.Full > 0 && b.Current >= b.Full
+	percent, err := b.Percent()
+	atThreshold := h.threshold > 0 && err == nil && float64(percent) >= h.threshold
+
+	var state AgnosticState
+	var sources []string
+	switch {
+	case hasRate && b.ChargeRate < 0:
+		state, sources = Discharging, []string{b.source(FieldChargeRate)}
+	case offline:
+		state, sources = Discharging, []string{h.onlineSource}
+	case atFull:
+		state, sources = Full, []string{b.source(FieldCurrent), b.source(FieldFull)}
+	case online && atThreshold:
+		state, sources = Idle, []string{h.onlineSource, h.thresholdSource}
+	case online && hasRate && b.ChargeRate > 0:
+		state, sources = Charging, []string{h.onlineSource, b.source(FieldChargeRate)}
+	case online:
+		state, sources = Idle, []string{h.onlineSource}
+	default:
+		return
+	}
+
+	b.State.Raw = state
+	b.State.Inferred = true
+	b.setSource(FieldState, nil, Derived, append([]string{b.source(FieldState)}, sources...)...)
+}
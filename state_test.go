@@ -0,0 +1,84 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInferState(t *testing.T) {
+	cases := []struct {
+		batteryIn *Battery
+		errorIn   ErrPartial
+		hints     stateHints
+		stateOut  State
+	}{{
+		&Battery{State: State{Raw: Unknown}, ChargeRate: -1000},
+		ErrPartial{},
+		onlineHints(true, "online"),
+		State{Raw: Discharging, Inferred: true},
+	}, {
+		&Battery{State: State{Raw: Undefined, specific: "5"}, ChargeRate: 1000},
+		ErrPartial{},
+		onlineHints(false, "online"),
+		State{Raw: Discharging, specific: "5", Inferred: true},
+	}, {
+		&Battery{State: State{Raw: Undefined}, Current: 40000, Full: 40000},
+		ErrPartial{},
+		stateHints{},
+		State{Raw: Full, Inferred: true},
+	}, {
+		&Battery{State: State{Raw: Unknown}, Current: 20000, Full: 40000, ChargeRate: 1000},
+		ErrPartial{},
+		onlineHints(true, "online"),
+		State{Raw: Charging, Inferred: true},
+	}, {
+		&Battery{State: State{Raw: Unknown}, Current: 20000, Full: 40000},
+		ErrPartial{},
+		onlineHints(true, "online"),
+		State{Raw: Idle, Inferred: true},
+	}, {
+		&Battery{State: State{Raw: Unknown}, Current: 20000, Full: 40000, ChargeRate: 1000},
+		ErrPartial{},
+		stateHints{},
+		State{Raw: Unknown},
+	}, {
+		&Battery{State: State{Raw: Unknown}, ChargeRate: -1000},
+		ErrPartial{State: errors.New("t1")},
+		stateHints{},
+		State{Raw: Unknown},
+	}, {
+		&Battery{State: State{Raw: Idle}, ChargeRate: -1000},
+		ErrPartial{},
+		onlineHints(false, "online"),
+		State{Raw: Idle},
+	}}
+
+	for i, c := range cases {
+		inferState(c.batteryIn, &c.errorIn, c.hints)
+
+		if c.batteryIn.State != c.stateOut {
+			t.Errorf("%d: %#v != %#v", i, c.batteryIn.State, c.stateOut)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Energy type represents an amount of energy (in mWh), as used by Battery capacity fields.
+type Energy float64
+
+// Power type represents a rate of energy transfer (in mW), as used by Battery.ChargeRate.
+type Power float64
+
+// Voltage type represents an electric potential (in V).
+type Voltage float64
+
+// Percent type represents a percentage, e.g. 75 for 75%.
+type Percent float64
+
+// Wh returns the energy in watt-hours.
+func (e Energy) Wh() float64 {
+	return float64(e) / 1000
+}
+
+// Joules returns the energy in joules.
+func (e Energy) Joules() float64 {
+	return float64(e) * 3.6
+}
+
+// Div returns how long it takes to transfer the energy at power p.
+// For zero power the longest possible duration is returned, as it never finishes.
+func (e Energy) Div(p Power) time.Duration {
+	if p == 0 {
+		return math.MaxInt64
+	}
+	hours := float64(e) / float64(p)
+	if math.Abs(hours) >= float64(math.MaxInt64)/float64(time.Hour) {
+		if hours < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+	return time.Duration(hours * float64(time.Hour))
+}
+
+func (e Energy) String() string {
+	return formatScaled(float64(e), "Wh")
+}
+
+// Watts returns the power in watts.
+func (p Power) Watts() float64 {
+	return float64(p) / 1000
+}
+
+// Mul returns the energy transferred at power p during d.
+func (p Power) Mul(d time.Duration) Energy {
+	return Energy(float64(p) * d.Hours())
+}
+
+func (p Power) String() string {
+	return formatScaled(float64(p), "W")
+}
+
+// Volts returns the voltage in volts.
+func (v Voltage) Volts() float64 {
+	return float64(v)
+}
+
+// Millivolts returns the voltage in millivolts.
+func (v Voltage) Millivolts() float64 {
+	return float64(v) * 1000
+}
+
+func (v Voltage) String() string {
+	return formatScaled(float64(v)*1000, "V")
+}
+
+// Fraction returns the percentage as a fraction, e.g. 0.75 for 75%.
+func (p Percent) Fraction() float64 {
+	return float64(p) / 100
+}
+
+func (p Percent) String() string {
+	return strconv.FormatFloat(float64(p), 'f', -1, 64) + "%"
+}
+
+// formatScaled formats value given in milli units with the most fitting prefix.
+func formatScaled(milli float64, unit string) string {
+	abs := math.Abs(milli)
+	switch {
+	case abs >= 1000_000:
+		return fmt.Sprintf("%.4gk%s", milli/1000_000, unit)
+	case abs >= 1000 || abs == 0:
+		return fmt.Sprintf("%.4g%s", milli/1000, unit)
+	default:
+		return fmt.Sprintf("%.4gm%s", milli, unit)
+	}
+}
+
+// parseUnit parses a number followed by one of the units (optionally space separated),
+// returning the number multiplied by the unit factor.
+func parseUnit(s string, units map[string]float64) (float64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.LastIndexAny(s, "0123456789.") + 1
+	num, unit := s[:i], strings.TrimSpace(s[i:])
+	factor, ok := units[unit]
+	if !ok {
+		return 0, fmt.Errorf("battery: unknown unit in %q", s)
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("battery: invalid number in %q", s)
+	}
+	return v * factor, nil
+}
+
+var energyUnits = map[string]float64{"mWh": 1, "Wh": 1000, "kWh": 1000_000, "J": 1 / 3.6, "kJ": 1000 / 3.6}
+
+// ParseEnergy parses energy value such as "45.2Wh", "520 mWh" or "3600J".
+func ParseEnergy(s string) (Energy, error) {
+	v, err := parseUnit(s, energyUnits)
+	return Energy(v), err
+}
+
+var powerUnits = map[string]float64{"mW": 1, "W": 1000, "kW": 1000_000}
+
+// ParsePower parses power value such as "12 W" or "850mW".
+func ParsePower(s string) (Power, error) {
+	v, err := parseUnit(s, powerUnits)
+	return Power(v), err
+}
+
+var voltageUnits = map[string]float64{"mV": 0.001, "V": 1}
+
+// ParseVoltage parses voltage value such as "11.8V" or "11800 mV".
+func ParseVoltage(s string) (Voltage, error) {
+	v, err := parseUnit(s, voltageUnits)
+	return Voltage(v), err
+}
+
+// ParsePercent parses percentage value such as "75%" or "75.5 %".
+func ParsePercent(s string) (Percent, error) {
+	v, err := parseUnit(s, map[string]float64{"%": 1})
+	return Percent(v), err
+}
+
+// Quantities type is a typed view of Battery values.
+type Quantities struct {
+	Capacity      Percent
+	Current       Energy
+	Full          Energy
+	Design        Energy
+	ChargeRate    Power
+	Voltage       Voltage
+	DesignVoltage Voltage
+	NetPower      Power
+}
+
+// Quantities returns battery values as typed quantities.
+func (b *Battery) Quantities() Quantities {
+	return Quantities{
+		Capacity:      Percent(b.Capacity),
+		Current:       Energy(b.Current),
+		Full:          Energy(b.Full),
+		Design:        Energy(b.Design),
+		ChargeRate:    Power(b.ChargeRate),
+		Voltage:       Voltage(b.Voltage),
+		DesignVoltage: Voltage(b.DesignVoltage),
+		NetPower:      Power(b.NetPower),
+	}
+}
@@ -0,0 +1,123 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestUnitsString(t *testing.T) {
+	cases := []struct {
+		in  fmt.Stringer
+		out string
+	}{
+		{Energy(45200), "45.2Wh"},
+		{Energy(520), "520mWh"},
+		{Energy(1234567), "1.235kWh"},
+		{Energy(0), "0Wh"},
+		{Power(12000), "12W"},
+		{Power(-850), "-850mW"},
+		{Voltage(11.8), "11.8V"},
+		{Voltage(0.5), "500mV"},
+		{Percent(75.5), "75.5%"},
+	}
+
+	for i, c := range cases {
+		if s := c.in.String(); s != c.out {
+			t.Errorf("%d: %v != %v", i, s, c.out)
+		}
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	cases := []struct {
+		in    string
+		parse func(string) (float64, error)
+		out   float64
+		isErr bool
+	}{
+		{"45.2Wh", parseEnergy, 45200, false},
+		{"520 mWh", parseEnergy, 520, false},
+		{"3.6J", parseEnergy, 1, false},
+		{"12 W", parsePower, 12000, false},
+		{" 850mW ", parsePower, 850, false},
+		{"11800mV", parseVoltage, 11.8, false},
+		{"75%", parsePercent, 75, false},
+		{"12", parsePower, 0, true},
+		{"12 Wh", parsePower, 0, true},
+		{"W", parsePower, 0, true},
+	}
+
+	for i, c := range cases {
+		v, err := c.parse(c.in)
+		if (err != nil) != c.isErr {
+			t.Errorf("%d: %v, expected error: %v", i, err, c.isErr)
+		}
+		if math.Abs(v-c.out) > 1e-9 {
+			t.Errorf("%d: %v != %v", i, v, c.out)
+		}
+	}
+}
+
+func parseEnergy(s string) (float64, error) {
+	v, err := ParseEnergy(s)
+	return float64(v), err
+}
+
+func parsePower(s string) (float64, error) {
+	v, err := ParsePower(s)
+	return float64(v), err
+}
+
+func parseVoltage(s string) (float64, error) {
+	v, err := ParseVoltage(s)
+	return float64(v), err
+}
+
+func parsePercent(s string) (float64, error) {
+	v, err := ParsePercent(s)
+	return float64(v), err
+}
+
+func TestEnergyDiv(t *testing.T) {
+	cases := []struct {
+		energy Energy
+		power  Power
+		out    time.Duration
+	}{
+		{45000, 12000, 3*time.Hour + 45*time.Minute},
+		{1000, 4000, 15 * time.Minute},
+		{1000, 0, math.MaxInt64},
+	}
+
+	for i, c := range cases {
+		if d := c.energy.Div(c.power); d != c.out {
+			t.Errorf("%d: %v != %v", i, d, c.out)
+		}
+	}
+	if e := Power(12000).Mul(30 * time.Minute); e != 6000 {
+		t.Errorf("%v != %v", e, Energy(6000))
+	}
+}
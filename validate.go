@@ -0,0 +1,186 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import "fmt"
+
+// Validation type selects what is done about implausible readings.
+type Validation int8
+
+const (
+	// ValidateReport records implausible readings in Battery.Warnings,
+	// leaving the values as they are.
+	ValidateReport Validation = iota
+	// ValidateNormalize records the warnings and corrects the values, see Battery.Normalize.
+	ValidateNormalize
+	// ValidateOff disables the checks.
+	ValidateOff
+)
+
+// WarningKind type enumerates kinds of implausible readings.
+type WarningKind int8
+
+const (
+	// WarnNegative is reported for a value that cannot be negative.
+	WarnNegative WarningKind = iota
+	// WarnOutOfRange is reported for Capacity above 100%.
+	WarnOutOfRange
+	// WarnCurrentAboveFull is reported for Current above Full, or ChargeNow above ChargeFull.
+	WarnCurrentAboveFull
+	// WarnFullAboveDesign is reported for Full above twice the Design,
+	// or ChargeFull above twice the ChargeDesign.
+	WarnFullAboveDesign
+	// WarnZeroVoltage is reported for zero Voltage,
+	// which zeroes all the values converted from charge.
+	WarnZeroVoltage
+)
+
+var warningKinds = map[WarningKind]string{
+	WarnNegative:         "Negative",
+	WarnOutOfRange:       "OutOfRange",
+	WarnCurrentAboveFull: "CurrentAboveFull",
+	WarnFullAboveDesign:  "FullAboveDesign",
+	WarnZeroVoltage:      "ZeroVoltage",
+}
+
+func (k WarningKind) String() string {
+	return warningKinds[k]
+}
+
+// Warning type describes an implausible reading.
+type Warning struct {
+	Kind  WarningKind
+	Field Field
+	// Value as it was read, before any correction.
+	Value float64
+	// Whether the value was corrected.
+	Fixed bool
+}
+
+func (w Warning) String() string {
+	s := fmt.Sprintf("%s: %s (%v)", w.Field, w.Kind, w.Value)
+	if w.Fixed {
+		s += ", fixed"
+	}
+	return s
+}
+
+// nonNegativeFields are checked for WarnNegative. Rates are left out,
+// as their sign is normalized separately.
+var nonNegativeFields = []Field{
+	FieldCapacity, FieldCurrent, FieldFull, FieldDesign, FieldVoltage, FieldDesignVoltage,
+	FieldChargeNow, FieldChargeFull, FieldChargeDesign,
+}
+
+// Validate returns implausible readings found in b, without changing it.
+func (b *Battery) Validate() []Warning {
+	return b.check(false)
+}
+
+// Normalize returns implausible readings found in b, same as Validate,
+// correcting the values where possible.
+//
+// Negative values are set to zero, Capacity is clamped to 100% and Current to Full.
+// Zero Voltage is replaced with DesignVoltage and the values converted from charge
+// are recalculated. Full above twice the Design is only reported, as there is no
+// telling which one of them is wrong.
+func (b *Battery) Normalize() []Warning {
+	return b.check(true)
+}
+
+func (b *Battery) check(fix bool) []Warning {
+	var warnings []Warning
+	warn := func(kind WarningKind, f Field, fixed bool) {
+		warnings = append(warnings, Warning{kind, f, *b.float(f), fixed})
+	}
+
+	for _, f := range nonNegativeFields {
+		if b.has(f) && *b.float(f) < 0 {
+			warn(WarnNegative, f, fix)
+			if fix {
+				*b.float(f) = 0
+			}
+		}
+	}
+
+	if b.has(FieldCapacity) && b.Capacity > 100 {
+		warn(WarnOutOfRange, FieldCapacity, fix)
+		if fix {
+			b.Capacity = 100
+		}
+	}
+
+	if b.has(FieldVoltage) && b.Voltage == 0 {
+		fixed := fix && b.has(FieldDesignVoltage) && b.DesignVoltage > 0
+		warn(WarnZeroVoltage, FieldVoltage, fixed)
+		if fixed {
+			b.Voltage = b.DesignVoltage
+			b.setSource(FieldVoltage, nil, Copied, b.source(FieldDesignVoltage))
+			b.reconvert()
+		}
+	}
+
+	for _, p := range []struct{ current, full Field }{
+		{FieldCurrent, FieldFull},
+		{FieldChargeNow, FieldChargeFull},
+	} {
+		if !b.has(p.current) || !b.has(p.full) {
+			continue
+		}
+		current, full := b.float(p.current), b.float(p.full)
+		if *full > 0 && *current > *full {
+			warn(WarnCurrentAboveFull, p.current, fix)
+			if fix {
+				*current = *full
+			}
+		}
+	}
+
+	for _, p := range []struct{ full, design Field }{
+		{FieldFull, FieldDesign},
+		{FieldChargeFull, FieldChargeDesign},
+	} {
+		if !b.has(p.full) || !b.has(p.design) {
+			continue
+		}
+		if design := *b.float(p.design); design > 0 && *b.float(p.full) > 2*design {
+			warn(WarnFullAboveDesign, p.full, false)
+		}
+	}
+
+	return warnings
+}
+
+// reconvert recalculates energy values zeroed by conversion
+// of natively read charge with zero voltage.
+func (b *Battery) reconvert() {
+	for _, c := range chargeFields {
+		if b.Provenance[c.charge].Origin != Measured || !b.has(c.charge) {
+			continue
+		}
+		if *b.float(c.energy) != 0 || *b.float(c.charge) == 0 {
+			continue
+		}
+		*b.float(c.energy) = *b.float(c.charge) * b.DesignVoltage
+		b.setSource(c.energy, nil, Derived, b.source(c.charge), b.source(FieldDesignVoltage))
+	}
+}
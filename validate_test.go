@@ -0,0 +1,130 @@
+// battery
+// Copyright (C) 2023 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package battery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		batteryIn    *Battery
+		warnings     []Warning
+		validateOut  *Battery
+		normalizeOut *Battery
+	}{{
+		&Battery{Capacity: 50, Current: 20000, Full: 40000, Design: 42000, Voltage: 12, DesignVoltage: 11.1},
+		nil,
+		&Battery{Capacity: 50, Current: 20000, Full: 40000, Design: 42000, Voltage: 12, DesignVoltage: 11.1},
+		&Battery{Capacity: 50, Current: 20000, Full: 40000, Design: 42000, Voltage: 12, DesignVoltage: 11.1},
+	}, {
+		&Battery{Capacity: 104, Current: 41600, Full: 40000, Design: -1, Voltage: 12, DesignVoltage: 11.1},
+		[]Warning{
+			{WarnNegative, FieldDesign, -1, true},
+			{WarnOutOfRange, FieldCapacity, 104, true},
+			{WarnCurrentAboveFull, FieldCurrent, 41600, true},
+		},
+		&Battery{Capacity: 104, Current: 41600, Full: 40000, Design: -1, Voltage: 12, DesignVoltage: 11.1},
+		&Battery{Capacity: 100, Current: 40000, Full: 40000, Design: 0, Voltage: 12, DesignVoltage: 11.1},
+	}, {
+		&Battery{Current: 20000, Full: 90000, Design: 40000, Voltage: 12, DesignVoltage: 11.1},
+		[]Warning{{WarnFullAboveDesign, FieldFull, 90000, false}},
+		&Battery{Current: 20000, Full: 90000, Design: 40000, Voltage: 12, DesignVoltage: 11.1},
+		&Battery{Current: 20000, Full: 90000, Design: 40000, Voltage: 12, DesignVoltage: 11.1},
+	}, {
+		&Battery{
+			DesignVoltage: 12, ChargeNow: 2000, ChargeFull: 4000,
+			Provenance: map[Field]Provenance{
+				FieldCurrent:       {Derived, "charge_now, voltage_now"},
+				FieldFull:          {Derived, "charge_full, voltage_now"},
+				FieldVoltage:       {Measured, "voltage_now"},
+				FieldDesignVoltage: {Measured, "voltage_min_design"},
+				FieldChargeNow:     {Measured, "charge_now"},
+				FieldChargeFull:    {Measured, "charge_full"},
+			},
+		},
+		[]Warning{{WarnZeroVoltage, FieldVoltage, 0, true}},
+		&Battery{
+			DesignVoltage: 12, ChargeNow: 2000, ChargeFull: 4000,
+			Provenance: map[Field]Provenance{
+				FieldCurrent:       {Derived, "charge_now, voltage_now"},
+				FieldFull:          {Derived, "charge_full, voltage_now"},
+				FieldVoltage:       {Measured, "voltage_now"},
+				FieldDesignVoltage: {Measured, "voltage_min_design"},
+				FieldChargeNow:     {Measured, "charge_now"},
+				FieldChargeFull:    {Measured, "charge_full"},
+			},
+		},
+		&Battery{
+			Current: 24000, Full: 48000, Voltage: 12, DesignVoltage: 12, ChargeNow: 2000, ChargeFull: 4000,
+			Provenance: map[Field]Provenance{
+				FieldCurrent:       {Derived, "charge_now, voltage_min_design"},
+				FieldFull:          {Derived, "charge_full, voltage_min_design"},
+				FieldVoltage:       {Copied, "voltage_min_design"},
+				FieldDesignVoltage: {Measured, "voltage_min_design"},
+				FieldChargeNow:     {Measured, "charge_now"},
+				FieldChargeFull:    {Measured, "charge_full"},
+			},
+		},
+	}}
+
+	for i, c := range cases {
+		validated := *c.batteryIn
+		validated.Provenance = copyProvenance(c.batteryIn.Provenance)
+		warnings := validated.Validate()
+
+		var want []Warning
+		for _, w := range c.warnings {
+			w.Fixed = false
+			want = append(want, w)
+		}
+		if !reflect.DeepEqual(warnings, want) {
+			t.Errorf("%d: %v != %v", i, warnings, want)
+		}
+		if !reflect.DeepEqual(&validated, c.validateOut) {
+			t.Errorf("%d: %v != %v", i, &validated, c.validateOut)
+		}
+
+		normalized := *c.batteryIn
+		normalized.Provenance = copyProvenance(c.batteryIn.Provenance)
+		warnings = normalized.Normalize()
+
+		if !reflect.DeepEqual(warnings, c.warnings) {
+			t.Errorf("%d: %v != %v", i, warnings, c.warnings)
+		}
+		if !reflect.DeepEqual(&normalized, c.normalizeOut) {
+			t.Errorf("%d: %v != %v", i, &normalized, c.normalizeOut)
+		}
+	}
+}
+
+func copyProvenance(p map[Field]Provenance) map[Field]Provenance {
+	if p == nil {
+		return nil
+	}
+	out := make(map[Field]Provenance, len(p))
+	for f, v := range p {
+		out[f] = v
+	}
+	return out
+}